@@ -2,6 +2,7 @@ package main
 
 import (
 	"image/color"
+	"pathfinding/solver"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/text"
@@ -15,7 +16,7 @@ type Button struct {
 	op         ebiten.DrawImageOptions
 	title      string
 	titleW     int
-	grid       Grid
+	grid       solver.Grid
 	buttonIcon *ebiten.Image
 	fontFace   font.Face
 
@@ -110,6 +111,12 @@ func (b *Button) Draw(screen *ebiten.Image) {
 	screen.DrawImage(b.rect, &b.op)
 }
 
+// SetTitle changes the text shown on the button, keeping it centered.
+func (b *Button) SetTitle(title string) {
+	b.title = title
+	b.titleW = text.BoundString(mononokiFFace, title).Dx()
+}
+
 func (b *Button) hover(x, y int) {
 	b.hovered = !b.disabled && x >= int(b.x) && x <= int(b.x)+b.w && y >= int(b.y) && y <= int(b.y)+b.h
 }
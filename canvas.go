@@ -1,30 +1,52 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"image"
 	"image/color"
 	"math"
+	"pathfinding/pair"
+	"pathfinding/solver"
+	"strings"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font"
 )
 
 type Canvas struct {
-	x, y   float64
-	w, h   int
-	rect   *ebiten.Image
-	op     ebiten.DrawImageOptions
-	title  string
-	titleW int
-	grid   Grid
+	x, y       float64
+	w, h       int
+	rect       *ebiten.Image
+	op         ebiten.DrawImageOptions
+	solverName string
+	grid       solver.Grid
+	search     *solver.Search
+	agent      *solver.Agent  // Walking the grid instead of search, in agent mode
+	planner    solver.Planner // Used instead of the solver when the grid has tasks
+	optimal    float64        // Cost of the shortest path, to flag suboptimal results
+
+	replanned         bool
+	replanSettled     bool // Whether the last replan was compared with a search from scratch, once done
+	replanFrom        int  // Iterations when the last replan started
+	scratchIterations int  // Iterations A* needs to solve the edited grid from scratch
+
+	time        int              // Time step shown of the tracks of a multi-agent search
+	multiStatus solver.Status    // Status of the multi-agent search, once its tracks are shown
+	planned     chan solver.Grid // Receives the grid planned by a multi-agent search still running, if any
+	cancel      context.CancelFunc
+
+	buttonPrevSolver, buttonNextSolver Button
 }
 
 func (c Canvas) TopLeftX() float64 {
 	return c.x
 }
 
-func NewCanvas(w, h int, x, y float64, title string) Canvas {
+func NewCanvas(w, h int, x, y float64, solverName string) Canvas {
 	rect := ebiten.NewImage(w, h)
 	rect.Fill(color.RGBA{25, 25, 25, 255})
 
@@ -32,73 +54,702 @@ func NewCanvas(w, h int, x, y float64, title string) Canvas {
 	op.GeoM.Translate(x, y)
 
 	return Canvas{
-		rect:  rect,
-		op:    op,
-		title: title,
-		x:     x, y: y, w: w, h: h,
-		titleW: text.BoundString(mononokiFFace, title).Dx(),
+		rect:       rect,
+		op:         op,
+		solverName: solverName,
+		x:          x, y: y, w: w, h: h,
+		buttonPrevSolver: NewButton(25, 25, x+float64(w)/2-130, y-28, "<", false, nil, mononokiFFace),
+		buttonNextSolver: NewButton(25, 25, x+float64(w)/2+105, y-28, ">", false, nil, mononokiFFace),
+	}
+}
+
+// CycleSolver selects the registered solver delta positions away from the current one,
+// or the planner if the grid has tasks.
+func (c *Canvas) CycleSolver(delta int) {
+	if len(c.grid.Tasks) > 0 {
+		c.planner = cycle(planners, c.planner, delta)
+	} else {
+		c.solverName = cycle(solver.Names(), c.solverName, delta)
+	}
+}
+
+// Title returns the name of the selected solver, or planner if the grid has tasks.
+func (c *Canvas) Title() string {
+	if len(c.grid.Tasks) > 0 {
+		return plannerTitles[c.planner]
+	}
+	return c.solverName
+}
+
+// StartSearch restarts the grid and begins searching it with the selected solver.
+func (c *Canvas) StartSearch() {
+	c.StopSearch()
+
+	var ctx context.Context
+	ctx, c.cancel = context.WithCancel(context.Background())
+
+	c.grid.Restart(true)
+	c.grid.Neighborhood = neighborhood
+	c.grid.Heuristic = heuristic
+	c.grid.HeuristicWeight = heuristicWeight
+	c.optimal, _ = c.grid.OptimalCost()
+	c.replanned = false
+	c.agent = nil
+
+	if len(c.grid.Tasks) > 0 {
+		c.startMulti()
+		return
+	}
+
+	s := solver.New(c.solverName)
+	if len(c.grid.Waypoints) > 0 {
+		s = solver.NewRouter(routeMode, func() solver.Solver { return solver.New(c.solverName) })
+		c.optimal = math.Inf(1) // Routes go through the waypoints, so the direct path is no optimum for them
+	}
+	c.search = solver.NewSearch(ctx, &c.grid, s, solver.Options{})
+}
+
+// startMulti plans the tracks of every task at once, then shows them moving one time step per search step.
+// Planning can take up to MULTI_TIMEOUT, so it runs on a clone of the grid away from the game goroutine,
+// and the canvas shows it as pathing until CheckPlan takes the planned grid.
+func (c *Canvas) startMulti() {
+	ctx, cancel := context.WithTimeout(context.Background(), MULTI_TIMEOUT)
+	c.cancel = cancel
+
+	c.search = nil
+	c.optimal = math.Inf(1) // The sum of costs has no single path optimum to compare with
+	c.time = 0
+
+	clone, planner := c.grid.Clone(), c.planner
+	planned := make(chan solver.Grid, 1)
+	c.planned = planned
+	go func() {
+		defer cancel()
+		solver.PlanMulti(ctx, &clone, planner, solver.Options{})
+		planned <- clone
+	}()
+
+	c.grid.StartTime = time.Now()
+	c.grid.Status = solver.STATUS_PATHING
+}
+
+// CheckPlan takes the grid of the multi-agent search once it is planned, and starts showing its tracks.
+func (c *Canvas) CheckPlan() {
+	if c.planned == nil {
+		return
+	}
+
+	select {
+	case grid := <-c.planned:
+		c.takePlan(grid)
+	default:
+	}
+}
+
+func (c *Canvas) takePlan(grid solver.Grid) {
+	c.planned = nil
+	c.grid = grid
+	c.multiStatus = c.grid.Status
+	if c.grid.Tracks != nil {
+		c.grid.Status = solver.STATUS_PATHING
+	}
+}
+
+// makespan returns the time step the last unit reaches its end at.
+func (c *Canvas) makespan() int {
+	makespan := 0
+	for _, track := range c.grid.Tracks {
+		makespan = max(makespan, len(track)-1)
+	}
+	return makespan
+}
+
+// StartAgent restarts the grid and places an agent on Start that walks to End, planning with the selected solver
+// and only knowing the walls within radius of the cells it has been on.
+func (c *Canvas) StartAgent(radius int) {
+	c.StopSearch()
+
+	c.grid.Restart(true)
+	c.grid.Neighborhood = neighborhood
+	c.grid.Heuristic = heuristic
+	c.grid.HeuristicWeight = heuristicWeight
+	c.optimal, _ = c.grid.OptimalCost()
+	c.replanned = false
+	c.search = nil
+	c.agent = solver.NewAgent(&c.grid, radius, func() solver.Solver { return solver.New(c.solverName) })
+}
+
+// CellChanged repairs the path of a finished or running incremental search after node was edited.
+// It reports whether a replan started; other solvers leave the grid as it is until the next search.
+func (c *Canvas) CellChanged(node *solver.Node) bool {
+	if c.search == nil || c.grid.Status == solver.STATUS_IDLE {
+		return false
+	}
+
+	if _, ok := c.search.Solver.(solver.IncrementalSolver); !ok {
+		return false
+	}
+
+	// Settings changed since the search started make the replan start over, as flags moved since do
+	c.grid.Neighborhood = neighborhood
+	c.grid.Heuristic = heuristic
+	c.grid.HeuristicWeight = heuristicWeight
+
+	c.optimal = math.Inf(1) // Until settleReplan finds it
+	c.replanFrom = c.grid.Iterations
+	c.replanned = true
+	c.replanSettled = false
+
+	return c.search.Replan(node)
+}
+
+// settleReplan compares the finished replan with A* from scratch, and finds the optimal cost to flag it with.
+// Draw runs it once the edits are over, rather than searching the grid twice for every cell painted.
+func (c *Canvas) settleReplan() {
+	scratch := c.grid.Clone()
+	c.scratchIterations = solver.Solve(context.Background(), &scratch, solver.New("A*"), solver.Options{}).Iterations
+	c.optimal, _ = c.grid.OptimalCost()
+	c.replanSettled = true
+}
+
+// StepSearch runs one iteration of the current search, moves the agent one cell or the units one time step,
+// or checks whether their tracks are planned, if there is one running.
+func (c *Canvas) StepSearch() {
+	if c.planned != nil {
+		c.CheckPlan()
+	} else if c.agent != nil {
+		c.agent.Tick()
+	} else if c.grid.Tracks != nil && c.grid.Status == solver.STATUS_PATHING {
+		c.time++
+		if c.time >= c.makespan() {
+			c.grid.Status = c.multiStatus
+		}
+	} else if c.search != nil {
+		c.search.Step()
+	}
+}
+
+// StopSearch cancels the current search or agent walk, if there is one running.
+func (c *Canvas) StopSearch() {
+	if c.planned != nil {
+		// The planner gives up soon after its context is cancelled, and its grid is left in the channel unread
+		c.cancel()
+		c.planned = nil
+		c.grid.EndTime = time.Now()
+		c.grid.Status = solver.STATUS_IDLE
+	}
+
+	if c.agent != nil {
+		c.agent.Stop()
+	} else if c.grid.Tracks != nil && c.grid.Status == solver.STATUS_PATHING {
+		c.time = c.makespan()
+		c.grid.Status = c.multiStatus
+	} else if c.search != nil {
+		c.cancel()
+		c.search.Step() // Apply the cancellation right away, even while paused
 	}
 }
 
-func (c *Canvas) SetGrid(grid Grid) {
-	canvasSize = len(grid.Cells)
-	cellSize = (c.w - len(grid.Cells)) / len(grid.Cells)
+func (c *Canvas) SetGrid(grid solver.Grid) {
+	canvasRows, canvasCols = len(grid.Cells), len(grid.Cells[0])
+	// Cells stay square, as big as the longer side of the grid allows, with a one pixel margin between them
+	cellSize = max(1, min((c.w-canvasCols)/canvasCols, (c.h-canvasRows)/canvasRows))
 	c.grid = grid
+	c.grid.Neighborhood = neighborhood
 }
 
 func (c *Canvas) Draw(screen *ebiten.Image) {
+	if c.replanned && !c.replanSettled && c.search.Done() && !drawing {
+		c.settleReplan()
+	}
+
+	stats, statsColor, extra := c.Stats()
+	lineY := c.drawCentered(screen, stats, int(c.y)+c.h+22, statsColor)
+	if extra != "" {
+		c.drawCentered(screen, extra, lineY, color.White)
+	}
+
+	c.rect.WritePixels(c.pixels())
+	screen.DrawImage(c.rect, &c.op)
+	c.drawFlowArrows(screen)
+	c.drawPathLines(screen)
+	c.drawAgent(screen)
+	c.drawVisits(screen)
+	c.drawTracks(screen)
+	if showClusters {
+		c.drawClusters(screen)
+	}
+
+	title := c.Title()
+	titleW := text.BoundString(mononokiFFace, title).Dx()
+	text.Draw(screen, title, mononokiFFace, int(c.x)+c.w/2-titleW/2, int(c.y)-7, color.White)
+	c.buttonPrevSolver.Draw(screen)
+	c.buttonNextSolver.Draw(screen)
+}
+
+// Stats returns the stats line of the search, in the color of its status, and a line about the agent,
+// the units or the last replan, if there is one.
+func (c *Canvas) Stats() (string, color.RGBA, string) {
 	textColor := color.RGBA{255, 255, 255, 255}
-	if c.grid.Status == STATUS_END_NOPATH {
+	if c.grid.Status == solver.STATUS_END_NOPATH {
 		textColor = color.RGBA{213, 60, 60, 255}
-	} else if c.grid.Status == STATUS_END_SUCCESS {
+	} else if c.grid.Status == solver.STATUS_END_SUCCESS {
 		textColor = color.RGBA{60, 213, 60, 255}
 	}
 
 	timeDiff := time.Now().Sub(c.grid.StartTime)
-	if c.grid.Status != STATUS_PATHING {
+	if c.grid.Status != solver.STATUS_PATHING {
 		timeDiff = c.grid.EndTime.Sub(c.grid.StartTime)
 	}
-	text.Draw(screen, fmt.Sprintf("Path length: %d | Iterations: %d | Time: %.2fs",
-		c.grid.PathLength, c.grid.Iterations, timeDiff.Seconds()), mononokiFFace, int(c.x)+c.w/2-240, int(c.y)+c.h+22, textColor)
+	cost := fmt.Sprintf("%.1f", c.grid.PathCost)
+	if c.grid.Status == solver.STATUS_END_SUCCESS && c.grid.PathCost > c.optimal+1e-9 {
+		cost += fmt.Sprintf(" (+%.0f%%)", (c.grid.PathCost-c.optimal)/c.optimal*100)
+		textColor = color.RGBA{230, 160, 50, 255}
+	}
+
+	stats := fmt.Sprintf("Length: %d | Cost: %s | Iterations: %d | Time: %.2fs",
+		c.grid.PathLength, cost, c.grid.Iterations, timeDiff.Seconds())
 
-	// Rect byte buffer
-	rowSize := c.w * 4
-	bytes := make([]byte, c.w*c.w*4)
+	extra := ""
+	if c.grid.Tracks != nil {
+		extra = fmt.Sprintf("Units: %d | Makespan: %d | Time step: %d", len(c.grid.Tracks), c.makespan(), min(c.time, c.makespan()))
+	} else if c.agent != nil {
+		extra = fmt.Sprintf("Agent: %d replans | Sensor radius: %d", c.agent.Replans, c.agent.Radius)
+	} else if c.replanned {
+		extra = fmt.Sprintf("Replan: %d re-expanded", c.grid.Iterations-c.replanFrom)
+		if c.replanSettled {
+			extra += fmt.Sprintf(" | A* from scratch: %d", c.scratchIterations)
+		}
+	}
 
-	bytes[0] = 255
+	return stats, textColor, extra
+}
 
-	for i := range bytes {
-		bytes[i] = 0x00
+// pixels returns the RGBA pixel buffer of the canvas, showing what the agent knows of the grid in agent mode.
+func (c *Canvas) pixels() []byte {
+	if c.agent != nil {
+		return gridPixels(&c.agent.Map, &c.grid, c.w, c.h, cellSize)
 	}
+	return gridPixels(&c.grid, nil, c.w, c.h, cellSize)
+}
 
-	for i, row := range c.grid.Cells {
+// Image returns the cells of the canvas as drawn, without the lines and circles drawn over them.
+// The pixels between cells, left transparent when drawing, are black as on the window. Square grids are cut
+// to their cells, so that every cell gets its share of the image when loading it back.
+func (c *Canvas) Image() *image.RGBA {
+	img := &image.RGBA{Pix: c.pixels(), Stride: c.w * 4, Rect: image.Rect(0, 0, c.w, c.h)}
+	for k := 3; k < len(img.Pix); k += 4 {
+		img.Pix[k] = 255
+	}
+	if c.grid.Neighborhood != solver.NEIGHBORHOOD_HEX {
+		img = img.SubImage(image.Rect(0, 0, canvasCols*(cellSize+1), canvasRows*(cellSize+1))).(*image.RGBA)
+	}
+	return img
+}
+
+// drawCentered draws str centered under the canvas at height y, and returns the height of the next line.
+func (c *Canvas) drawCentered(screen *ebiten.Image, str string, y int, clr color.Color) int {
+	face, lines, lineHeight := fitLine(str, c.w)
+	for _, line := range lines {
+		lineW := text.BoundString(face, line).Dx()
+		text.Draw(screen, line, face, int(c.x)+c.w/2-lineW/2, y, clr)
+		y += lineHeight
+	}
+	return y
+}
+
+// fitLine returns the face, lines and line height str is drawn with under a canvas width pixels wide.
+// Lines much wider than the canvas use the small font, and are split in two at a " | " if they still are.
+func fitLine(str string, width int) (font.Face, []string, int) {
+	face, lineHeight := mononokiFFace, 20
+	if font.MeasureString(face, str).Ceil() > width+40 {
+		face, lineHeight = mononokiFFaceSmall, 16
+	}
+
+	lines := []string{str}
+	if parts := strings.Split(str, " | "); font.MeasureString(face, str).Ceil() > width+40 && len(parts) > 1 {
+		half := (len(parts) + 1) / 2
+		lines = []string{strings.Join(parts[:half], " | "), strings.Join(parts[half:], " | ")}
+	}
+	return face, lines, lineHeight
+}
+
+// drawPathLines draws the found path as straight segments between the centers of its cells,
+// if it has links between cells that are not adjacent (any-angle paths).
+func (c *Canvas) drawPathLines(screen *ebiten.Image) {
+	if c.grid.Status != solver.STATUS_END_SUCCESS {
+		return
+	}
+
+	anyAngle := false
+	for node := c.grid.End; node.Prev != nil; node = node.Prev {
+		if !c.grid.Adjacent(*node.Prev, *node) {
+			anyAngle = true
+			break
+		}
+	}
+	if !anyAngle {
+		return
+	}
+
+	for node := c.grid.End; node.Prev != nil; node = node.Prev {
+		x0, y0 := c.cellCenter(node.Prev)
+		x1, y1 := c.cellCenter(node)
+		vector.StrokeLine(screen, x0, y0, x1, y1, 2, color.RGBA{255, 200, 40, 255}, true)
+	}
+}
+
+// drawFlowArrows draws on every cell of a flow field an arrow pointing to its downhill neighbour,
+// if the cells are big enough to tell them apart.
+func (c *Canvas) drawFlowArrows(screen *ebiten.Image) {
+	if c.pitch() < 9 {
+		return
+	}
+
+	arrowColor := color.RGBA{20, 20, 20, 200}
+	for i := range c.grid.Cells {
+		for j := range c.grid.Cells[i] {
+			node := &c.grid.Cells[i][j]
+			if node.Next == nil || node.IsPath {
+				continue
+			}
+
+			x, y := c.cellCenter(node)
+			nx, ny := c.cellCenter(node.Next)
+			dx, dy := nx-x, ny-y
+			length := float32(math.Hypot(float64(dx), float64(dy)))
+			dx, dy = dx/length*c.pitch()*0.35, dy/length*c.pitch()*0.35
+
+			vector.StrokeLine(screen, x-dx, y-dy, x+dx, y+dy, 1, arrowColor, true)
+			vector.StrokeLine(screen, x+dx, y+dy, x+dx*0.2-dy*0.5, y+dy*0.2+dx*0.5, 1, arrowColor, true)
+			vector.StrokeLine(screen, x+dx, y+dy, x+dx*0.2+dy*0.5, y+dy*0.2-dx*0.5, 1, arrowColor, true)
+		}
+	}
+}
+
+// drawAgent draws the trail walked by the agent, its sensor range and the agent itself, if there is one.
+func (c *Canvas) drawAgent(screen *ebiten.Image) {
+	if c.agent == nil {
+		return
+	}
+
+	for k := 1; k < len(c.agent.Trail); k++ {
+		x0, y0 := c.cellCenter(c.agent.Trail[k-1])
+		x1, y1 := c.cellCenter(c.agent.Trail[k])
+		vector.StrokeLine(screen, x0, y0, x1, y1, 2, color.RGBA{255, 200, 40, 255}, true)
+	}
+
+	x, y := c.cellCenter(c.agent.Pos)
+	radius := float32(c.agent.Radius)*c.pitch() + c.pitch()/2
+	vector.StrokeCircle(screen, x, y, radius, 1, color.RGBA{255, 200, 40, 160}, true)
+	vector.DrawFilledCircle(screen, x, y, c.pitch()/2+1, color.RGBA{255, 200, 40, 255}, true)
+}
+
+// drawVisits numbers the waypoints in the order the route found visits them.
+func (c *Canvas) drawVisits(screen *ebiten.Image) {
+	for k := 1; k < len(c.grid.Visits)-1; k++ {
+		x, y := c.cellCenter(c.grid.Visits[k])
+		label := fmt.Sprint(k)
+		text.Draw(screen, label, mononokiFFaceSmall, int(x)-text.BoundString(mononokiFFaceSmall, label).Dx()/2, int(y-c.pitch()/2)-2, color.White)
+	}
+}
+
+// drawClusters draws the cluster borders and abstract edges of the current search, if its solver is hierarchical.
+func (c *Canvas) drawClusters(screen *ebiten.Image) {
+	if c.search == nil {
+		return
+	}
+	hierarchical, ok := c.search.Solver.(solver.HierarchicalSolver)
+	if !ok {
+		return
+	}
+
+	// Cluster borders zigzag on hex grids, so only the abstract graph shows them there
+	if c.grid.Neighborhood != solver.NEIGHBORHOOD_HEX {
+		borderColor := color.RGBA{255, 255, 255, 140}
+		step := float32(hierarchical.ClusterSize() * (cellSize + 1))
+		w, h := float32(len(c.grid.Cells[0])*(cellSize+1)), float32(len(c.grid.Cells)*(cellSize+1))
+		for k := float32(1); k*step < h; k++ {
+			vector.StrokeLine(screen, float32(c.x), float32(c.y)+k*step, float32(c.x)+w, float32(c.y)+k*step, 1, borderColor, false)
+		}
+		for k := float32(1); k*step < w; k++ {
+			vector.StrokeLine(screen, float32(c.x)+k*step, float32(c.y), float32(c.x)+k*step, float32(c.y)+h, 1, borderColor, false)
+		}
+	}
+
+	for _, edge := range hierarchical.AbstractEdges() {
+		x0, y0 := c.cellCenter(&c.grid.Cells[edge[0].I][edge[0].J])
+		x1, y1 := c.cellCenter(&c.grid.Cells[edge[1].I][edge[1].J])
+		vector.StrokeLine(screen, x0, y0, x1, y1, 1, color.RGBA{240, 150, 40, 200}, true)
+		vector.DrawFilledCircle(screen, x0, y0, 2, color.RGBA{240, 150, 40, 255}, true)
+		vector.DrawFilledCircle(screen, x1, y1, 2, color.RGBA{240, 150, 40, 255}, true)
+	}
+}
+
+// drawTracks draws the track of every unit in its color, and each unit on its cell at the shown time step.
+func (c *Canvas) drawTracks(screen *ebiten.Image) {
+	for k, track := range c.grid.Tracks {
+		trackColor := unitColors[k%len(unitColors)]
+		offset := float32(k-len(c.grid.Tracks)/2) * 1.5 // Keep tracks sharing cells apart
+
+		for t := 1; t < len(track); t++ {
+			x0, y0 := c.cellCenter(&c.grid.Cells[track[t-1].I][track[t-1].J])
+			x1, y1 := c.cellCenter(&c.grid.Cells[track[t].I][track[t].J])
+			vector.StrokeLine(screen, x0+offset, y0+offset, x1+offset, y1+offset, 2, trackColor, true)
+		}
+
+		p := track.At(c.time)
+		x, y := c.cellCenter(&c.grid.Cells[p.I][p.J])
+		vector.DrawFilledCircle(screen, x, y, c.pitch()/2+1, trackColor, true)
+		vector.StrokeCircle(screen, x, y, c.pitch()/2+1, 1, color.White, true)
+	}
+}
+
+// drawUnitStart outlines the start of the unit being placed, in the color it will have.
+func (c *Canvas) drawUnitStart(screen *ebiten.Image, p pair.Pair) {
+	x, y := c.cellCenter(&c.grid.Cells[p.I][p.J])
+	vector.StrokeCircle(screen, x, y, c.pitch()/2+1, 2, unitColors[len(c.grid.Tasks)%len(unitColors)], true)
+}
+
+// cellCenter returns the screen position of the center of node.
+func (c *Canvas) cellCenter(n *solver.Node) (float32, float32) {
+	if c.grid.Neighborhood == solver.NEIGHBORHOOD_HEX {
+		size := hexSize(len(c.grid.Cells), len(c.grid.Cells[0]), c.w, c.h)
+		x, y := hexCenter(n.Coord, size)
+		return float32(c.x + x), float32(c.y + y)
+	}
+
+	return float32(c.x) + float32(n.Coord.J*(cellSize+1)) + float32(cellSize)/2,
+		float32(c.y) + float32(n.Coord.I*(cellSize+1)) + float32(cellSize)/2
+}
+
+// pitch returns the distance between the centers of two neighbouring cells.
+func (c *Canvas) pitch() float32 {
+	if c.grid.Neighborhood == solver.NEIGHBORHOOD_HEX {
+		return float32(hexSize(len(c.grid.Cells), len(c.grid.Cells[0]), c.w, c.h))
+	}
+	return float32(cellSize + 1)
+}
+
+// gridPixels returns the RGBA pixel buffer of a w*h image showing every cell of grid.
+// If grid is what an agent knows of world, the walls of world it has not seen yet are shown dimmed.
+// It only reads the grid, so it must run on the same goroutine that steps its search (Update and Draw share one).
+func gridPixels(grid, world *solver.Grid, w, h, cellSize int) []byte {
+	rowSize := w * 4
+	bytes := make([]byte, w*h*4)
+
+	// Flow fields are shown as a gradient from End to the farthest cell reached
+	fieldMax := 0.0
+	for _, row := range grid.Cells {
+		for _, node := range row {
+			if node.Next != nil {
+				fieldMax = math.Max(fieldMax, node.Cost)
+			}
+		}
+	}
+
+	hex := grid.Neighborhood == solver.NEIGHBORHOOD_HEX
+	colors := make([]color.RGBA, len(grid.Cells)*len(grid.Cells[0]))
+
+	for i, row := range grid.Cells {
 		for j, node := range row {
-			nodeColor := color.RGBA{100, 100, 100, 255}
+			nodeColor := terrainColor(node.Weight)
 
 			if node.IsWall {
-				nodeColor = color.RGBA{30, 30, 30, 255}
-			} else if node.Coord == c.grid.Start.Coord {
-				nodeColor = color.RGBA{60, 213, 60, 255}
-			} else if node.Coord == c.grid.End.Coord {
-				nodeColor = color.RGBA{213, 60, 60, 255}
+				nodeColor = wallColor
+			} else if world != nil && world.Cells[i][j].IsWall {
+				nodeColor = unseenWallColor
+			} else if node.Coord == grid.Start.Coord {
+				nodeColor = startColor
+			} else if node.Coord == grid.End.Coord {
+				nodeColor = endColor
+			} else if k, isStart := grid.TaskAt(node.Coord); k != -1 {
+				nodeColor = unitColor(k, isStart)
+			} else if grid.IsWaypoint(node.Coord) {
+				nodeColor = waypointColor
 			} else if node.IsPath {
-				nodeColor = color.RGBA{255, 255, 255, 255}
+				nodeColor = pathColor
+			} else if node.Next != nil {
+				nodeColor = fieldColor(node.Cost / fieldMax)
+			} else if node.Visited && node.IsJumpPoint {
+				nodeColor = mixColors(visitedJumpColor, nodeColor, node.Weight)
+			} else if node.Added && node.IsJumpPoint {
+				nodeColor = mixColors(addedJumpColor, nodeColor, node.Weight)
+			} else if node.Visited && node.FromEnd {
+				nodeColor = mixColors(visitedFromEndColor, nodeColor, node.Weight)
+			} else if node.Added && node.FromEnd {
+				nodeColor = mixColors(addedFromEndColor, nodeColor, node.Weight)
 			} else if node.Visited {
-				nodeColor = color.RGBA{50, 139, 181, 255}
+				nodeColor = mixColors(visitedColor, nodeColor, node.Weight)
 			} else if node.Added {
-				nodeColor = color.RGBA{62, 190, 250, 255}
+				nodeColor = mixColors(addedColor, nodeColor, node.Weight)
 			}
 
-			drawNodePixels(i, j, cellSize, rowSize, &bytes, nodeColor)
+			if hex {
+				colors[i*len(row)+j] = nodeColor
+			} else {
+				drawNodePixels(i, j, cellSize, rowSize, &bytes, nodeColor)
+			}
 		}
 	}
 
-	c.rect.WritePixels(bytes)
-	screen.DrawImage(c.rect, &c.op)
-	text.Draw(screen, c.title, mononokiFFace, int(c.x)+c.w/2-c.titleW/2, 33, color.White)
+	if hex {
+		for index, cell := range hexMask(len(grid.Cells), len(grid.Cells[0]), w, h) {
+			if cell >= 0 {
+				copy(bytes[index*4:], []byte{colors[cell].R, colors[cell].G, colors[cell].B, colors[cell].A})
+			}
+		}
+	}
+
+	return bytes
+}
+
+// hexMasks caches the masks returned by hexMask, by grid and image dimensions.
+var hexMasks = map[[4]int][]int{}
+
+// hexMask returns, for every pixel of a w*h image showing a rows*cols hex grid, the index i*cols+j of the cell
+// it is part of, or -1 for pixels in the gaps between cells and outside the grid.
+func hexMask(rows, cols, w, h int) []int {
+	key := [4]int{rows, cols, w, h}
+	if mask, ok := hexMasks[key]; ok {
+		return mask
+	}
+
+	size := hexSize(rows, cols, w, h)
+	radius := size/math.Sqrt(3) - 0.75 // One pixel gap between cells, as on square grids
+
+	mask := make([]int, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			mask[y*w+x] = -1
+
+			p := hexAt(float64(x)+0.5, float64(y)+0.5, size)
+			if !p.InBounds(0, 0, rows, cols) {
+				continue
+			}
+
+			cx, cy := hexCenter(p, size)
+			dx, dy := math.Abs(float64(x)+0.5-cx), math.Abs(float64(y)+0.5-cy)
+			if dx <= math.Sqrt(3)/2*radius && dy <= radius-dx/math.Sqrt(3) {
+				mask[y*w+x] = p.I*cols + p.J
+			}
+		}
+	}
+
+	hexMasks[key] = mask
+	return mask
+}
+
+// hexSize returns the distance between the centers of two neighbouring cells of a rows*cols hex grid fitting in w*h.
+// Cells are pointy topped, so a grid spans (cols+0.5) sizes across and (rows-1)*sqrt(3)/2 + 2/sqrt(3) sizes down.
+func hexSize(rows, cols, w, h int) float64 {
+	return math.Min(float64(w)/(float64(cols)+0.5), float64(h)/(float64(rows-1)*math.Sqrt(3)/2+2/math.Sqrt(3)))
+}
+
+// hexCenter returns the position of the center of the hex cell p, relative to the top left corner of the grid.
+func hexCenter(p pair.Pair, size float64) (float64, float64) {
+	x, y := p.HexCenter()
+	return size * (x + 0.5), size*y + size/math.Sqrt(3)
+}
+
+// hexAt returns the hex cell containing the position (x, y), relative to the top left corner of the grid.
+func hexAt(x, y, size float64) pair.Pair {
+	x, y = x/size-0.5, (y-size/math.Sqrt(3))/size
+	r := y * 2 / math.Sqrt(3)
+	return pair.HexRound(r, x-r/2).Offset()
+}
+
+// Colors of the units of multi-agent searches, in the order of the tasks.
+var unitColors = []color.RGBA{
+	{230, 80, 80, 255},
+	{80, 200, 120, 255},
+	{90, 140, 240, 255},
+	{240, 200, 60, 255},
+	{200, 100, 220, 255},
+	{60, 210, 220, 255},
+	{240, 140, 60, 255},
+	{240, 240, 240, 255},
+}
+
+// unitColor returns the color of the start cell of the unit of task k, or the darker one of its end cell.
+func unitColor(k int, isStart bool) color.RGBA {
+	c := unitColors[k%len(unitColors)]
+	if !isStart {
+		c = color.RGBA{c.R / 2, c.G / 2, c.B / 2, 255}
+	}
+	return c
+}
+
+// Colors of the cells, by what is on them or what the search did with them.
+var (
+	wallColor       = color.RGBA{30, 30, 30, 255}
+	unseenWallColor = color.RGBA{65, 65, 65, 255} // Walls of the world an agent has not seen yet
+	startColor      = color.RGBA{60, 213, 60, 255}
+	endColor        = color.RGBA{213, 60, 60, 255}
+	waypointColor   = color.RGBA{240, 150, 40, 255}
+	pathColor       = color.RGBA{255, 255, 255, 255}
+
+	visitedColor        = color.RGBA{50, 139, 181, 255}
+	addedColor          = color.RGBA{62, 190, 250, 255}
+	visitedJumpColor    = color.RGBA{230, 190, 50, 255}
+	addedJumpColor      = color.RGBA{250, 225, 120, 255}
+	visitedFromEndColor = color.RGBA{150, 90, 180, 255}
+	addedFromEndColor   = color.RGBA{205, 135, 245, 255}
+)
+
+// cellColors returns every color cells are drawn with, but for the gradient of flow fields.
+func cellColors() []color.RGBA {
+	colors := []color.RGBA{wallColor, unseenWallColor, startColor, endColor, waypointColor, pathColor}
+	for _, weight := range []int{solver.WEIGHT_ROAD, solver.WEIGHT_GRASS, solver.WEIGHT_MUD, solver.WEIGHT_WATER} {
+		terrain := terrainColor(weight)
+		colors = append(colors, terrain)
+		for _, state := range []color.RGBA{visitedColor, addedColor, visitedJumpColor, addedJumpColor, visitedFromEndColor, addedFromEndColor} {
+			colors = append(colors, mixColors(state, terrain, weight))
+		}
+	}
+	for k := range unitColors {
+		colors = append(colors, unitColor(k, true), unitColor(k, false))
+	}
+	return colors
+}
+
+// fieldColor returns the color of a flow field cell at the given fraction of the farthest distance to End.
+func fieldColor(fraction float64) color.RGBA {
+	near, far := color.RGBA{250, 220, 90, 255}, color.RGBA{40, 60, 140, 255}
+	lerp := func(a, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*fraction)
+	}
+	return color.RGBA{lerp(near.R, far.R), lerp(near.G, far.G), lerp(near.B, far.B), 255}
+}
+
+// terrainColor returns the color of an empty cell with the given weight.
+func terrainColor(weight int) color.RGBA {
+	switch {
+	case weight <= solver.WEIGHT_ROAD:
+		return color.RGBA{100, 100, 100, 255}
+	case weight <= solver.WEIGHT_GRASS:
+		return color.RGBA{86, 130, 64, 255}
+	case weight <= solver.WEIGHT_MUD:
+		return color.RGBA{120, 88, 52, 255}
+	default:
+		return color.RGBA{40, 66, 130, 255}
+	}
+}
+
+// mixColors blends a search state color with the terrain color underneath, so weighted cells stay recognizable.
+func mixColors(state, terrain color.RGBA, weight int) color.RGBA {
+	if weight <= solver.BASE_WEIGHT {
+		return state
+	}
+	return color.RGBA{state.R/2 + terrain.R/2, state.G/2 + terrain.G/2, state.B/2 + terrain.B/2, 255}
 }
 
 func drawNodePixels(cellI, cellJ int, cellSize int, rowSize int, bytes *[]byte, cellColor color.RGBA) {
+	// Layouts and loads keep grids within the canvas, but never write past its right and bottom edges
+	if (cellJ+1)*(cellSize+1) > rowSize/4 || (cellI+1)*(cellSize+1)*rowSize > len(*bytes) {
+		return
+	}
+
 	for i := 0; i < cellSize; i++ {
 		for j := 0; j < cellSize; j++ {
 			index := i * rowSize                // Vertical displacement
@@ -117,20 +768,30 @@ func drawNodePixels(cellI, cellJ int, cellSize int, rowSize int, bytes *[]byte,
 	}
 }
 
-func mousePosCoords(canvasA, canvasB *Canvas, pos_x, pos_y int) (int, int, *Canvas) {
+func mousePosCoords(canvases []*Canvas, pos_x, pos_y int) (int, int, *Canvas) {
 	var clickedCanvas *Canvas = nil
 
-	if pos_x >= int(canvasA.x) && pos_x <= int(canvasA.x)+canvasA.w && pos_y >= int(canvasA.y) && pos_y <= int(canvasA.y)+canvasA.h {
-		clickedCanvas = canvasA
-	} else if pos_x >= int(canvasB.x) && pos_x <= int(canvasB.x)+canvasB.w && pos_y >= int(canvasB.y) && pos_y <= int(canvasA.y)+canvasB.h {
-		clickedCanvas = canvasB
+	for _, canvas := range canvases {
+		if pos_x >= int(canvas.x) && pos_x <= int(canvas.x)+canvas.w && pos_y >= int(canvas.y) && pos_y <= int(canvas.y)+canvas.h {
+			clickedCanvas = canvas
+			break
+		}
 	}
 
 	if clickedCanvas == nil {
 		return -1, -1, nil
 	}
 
-	relativeCellSize := (clickedCanvas.w) / len(clickedCanvas.grid.Cells)
+	if clickedCanvas.grid.Neighborhood == solver.NEIGHBORHOOD_HEX {
+		size := hexSize(len(clickedCanvas.grid.Cells), len(clickedCanvas.grid.Cells[0]), clickedCanvas.w, clickedCanvas.h)
+		p := hexAt(float64(pos_x)-clickedCanvas.x, float64(pos_y)-clickedCanvas.y, size)
+		if p.InBounds(0, 0, len(clickedCanvas.grid.Cells), len(clickedCanvas.grid.Cells[0])) {
+			return p.I, p.J, clickedCanvas
+		}
+		return -1, -1, nil
+	}
+
+	relativeCellSize := cellSize + 1
 	x, y := pos_x-int(clickedCanvas.x), pos_y-int(clickedCanvas.y)
 	j, i := int(math.Floor(float64(x)/float64(relativeCellSize))), int(math.Floor(float64(y)/float64(relativeCellSize)))
 
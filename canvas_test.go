@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"math/rand"
+	"pathfinding/pair"
+	"pathfinding/solver"
+	"testing"
+)
+
+// Searches mutate their grid, so the goroutine stepping one is also the one rendering it, as Update and Draw are,
+// and only hands the rendered frames to others. Draw reads the cells through gridPixels, so this covers it.
+// Run with -race to check nothing else reads the grid meanwhile.
+func TestRenderWhileSolving(t *testing.T) {
+	const size, cellSize = 30, 5
+	w := size * (cellSize + 1)
+
+	grid := solver.NewGrid(size, size, pair.New(size-1, 0), pair.New(0, size-1))
+	for i := 5; i < size; i++ {
+		grid.Cells[i][size/2].IsWall = true
+	}
+
+	frames := make(chan []byte)
+	go func() {
+		defer close(frames)
+		search := solver.NewSearch(context.Background(), &grid, solver.New("A*"), solver.Options{})
+		for !search.Done() {
+			search.Step()
+			frames <- gridPixels(&grid, nil, w, w, cellSize)
+		}
+	}()
+
+	var last []byte
+	count := 0
+	for frame := range frames {
+		if len(frame) != w*w*4 {
+			t.Fatalf("frame %d has %d bytes, want %d", count, len(frame), w*w*4)
+		}
+		last = frame
+		count++
+	}
+
+	// The search is over once frames is closed, so the grid can be read here
+	if grid.Status != solver.STATUS_END_SUCCESS {
+		t.Fatalf("search ended with %s after %d frames", grid.Status, count)
+	}
+	if count != grid.Iterations {
+		t.Errorf("got %d frames for %d iterations", count, grid.Iterations)
+	}
+
+	for _, row := range grid.Cells {
+		for _, node := range row {
+			if !node.IsPath || node.Coord == grid.Start.Coord || node.Coord == grid.End.Coord {
+				continue
+			}
+			// Top left pixel of the cell, which the last frame shows in white as part of the path
+			k := node.Coord.I*(cellSize+1)*w*4 + node.Coord.J*(cellSize+1)*4
+			if last[k] != 255 || last[k+1] != 255 || last[k+2] != 255 {
+				t.Fatalf("path cell %v is drawn as %v", node.Coord, last[k:k+4])
+			}
+		}
+	}
+}
+
+// Saved canvases load back with the walls and flags they show, whatever room the grid leaves in the canvas.
+func TestPNGRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for _, size := range [][2]int{{12, 20}, {25, 9}, {40, 40}} {
+		rows, cols := size[0], size[1]
+		grid := solver.NewGrid(rows, cols, pair.New(rows-1, 0), pair.New(0, cols-1))
+		for i := range grid.Cells {
+			for j := range grid.Cells[i] {
+				grid.Cells[i][j].IsWall = !grid.IsFlag(pair.New(i, j)) && rng.Intn(3) == 0
+				grid.Cells[i][j].Weight = []int{solver.WEIGHT_ROAD, solver.WEIGHT_GRASS}[rng.Intn(2)]
+			}
+		}
+
+		c := &Canvas{w: 300, h: 200}
+		c.SetGrid(grid)
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, c.Image()); err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		read, err := solver.GridFromImage(img, rows, cols, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := range grid.Cells {
+			for j, node := range grid.Cells[i] {
+				if read.Cells[i][j].IsWall != node.IsWall {
+					t.Fatalf("%dx%d grid: cell %v read as wall %t", cols, rows, node.Coord, read.Cells[i][j].IsWall)
+				}
+			}
+		}
+		if read.Start.Coord != grid.Start.Coord || read.End.Coord != grid.End.Coord {
+			t.Fatalf("%dx%d grid: flags read at %v and %v, saved at %v and %v", cols, rows, read.Start.Coord, read.End.Coord, grid.Start.Coord, grid.End.Coord)
+		}
+	}
+}
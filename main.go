@@ -3,13 +3,24 @@ package main
 import (
 	"bytes"
 	"embed"
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
-	_ "image/png"
+	"image/png"
+	"io"
+	"io/fs"
 	"log"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"pathfinding/pair"
+	"pathfinding/solver"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
@@ -22,43 +33,84 @@ import (
 // WINDOW CONSTANTS
 const (
 	SCREEN_WIDTH  = 1400
-	SCREEN_HEIGHT = 640
+	SCREEN_HEIGHT = 745
 )
 
 // TOOL STATUS
 var (
-	activeTool Tool
-	drawing    bool
+	activeTool  Tool
+	drawing     bool
+	brushWeight int
 )
 
 // UI ELEMENTS
 var (
-	canvasA, canvasB Canvas
+	canvases []*Canvas // Sharing the same layout and flags, each searching it with its own solver
 
 	buttonPencil, buttonEraser, buttonFlagStart, buttonFlagEnd Button
+	buttonTerrain, buttonBrushWeight                           Button
 	buttonClearPath, buttonClearCanvas                         Button
 	buttonGenerateTerrain                                      Button
 	buttonTerrainSizeS, buttonTerrainSizeM, buttonTerrainSizeL Button
-	buttonPlay                                                 Button
+	buttonCustomSize                                           Button
+	buttonPlay, buttonPause, buttonStep                        Button
 	buttonMsMinus, buttonMsPlus                                Button
 	buttonGithub                                               Button
+	buttonNeighborhood, buttonHeuristic                        Button
+	buttonWeightMinus, buttonWeightPlus                        Button
+	buttonAgent, buttonSensorMinus, buttonSensorPlus           Button
+	buttonWaypoint, buttonRouteMode, buttonUnit                Button
+	buttonClusters                                             Button
+	buttonCanvasMinus, buttonCanvasPlus                        Button
+	buttonMapFile, buttonSave, buttonLoad                      Button
+	buttonGreyTerrain, buttonRecord                            Button
 
-	categoryTools, categoryClear, categoryTerrainSize, categoryCooldown string
+	categoryTools, categoryClear, categoryTerrainSize, categoryCooldown, categoryCanvases string
 )
 
 // OTHERS
 var (
-	canvasSize int
-	cellSize   int
+	canvasRows, canvasCols int
+	cellSize               int
+
+	activeInput Input  // Button taking typed input, if any
+	inputText   string // Typed so far into the active input
+
+	mapFile     string // File the map is saved to and loaded from
+	mapMessage  string // Result of the last save or load
+	greyTerrain bool   // Images loaded as maps turn grey into terrain weights, not only dark into walls
+
+	recordMode  bool        // Play records the solve to RECORD_FILE
+	recorder    *Recorder   // Recording the running solve, if any
+	recordSaved chan string // Message about a recording once it is saved in the background
 
 	iconGithub *ebiten.Image
 
 	mononokiFFace, mononokiFFaceSmall font.Face
-	stopSignal                        chan struct{}
+
+	neighborhood solver.Neighborhood
+	heuristic    solver.Heuristic
+
+	heuristicWeight float64
+
+	agentMode    bool // Play walks an agent along the path instead of only searching it
+	sensorRadius int
+
+	routeMode solver.RouteMode
+
+	showClusters bool // Draw the clusters and abstract graph of hierarchical solvers
+
+	placingUnit bool      // The start of a unit was clicked, and its end is next
+	unitStart   pair.Pair // Start of the unit being placed
 
 	iterationCooldownMS int
+	paused              bool
+	lastStepTime        time.Time
 )
 
+// Maximum solver iterations run per frame, reached when the cooldown is 0ms.
+const MAX_STEPS_PER_FRAME = 200
+
 // MOUSE TOOLS
 type Tool string
 
@@ -67,6 +119,70 @@ const (
 	ERASER     Tool = "ERASER"
 	FLAG_START Tool = "FLAG_START"
 	FLAG_END   Tool = "FLAG_END"
+	TERRAIN    Tool = "TERRAIN"
+	WAYPOINT   Tool = "WAYPOINT"
+	UNIT       Tool = "UNIT"
+)
+
+// TEXT INPUTS
+type Input string
+
+const (
+	INPUT_NONE Input = "INPUT_NONE"
+	INPUT_SIZE Input = "INPUT_SIZE" // Custom canvas size, as "<width>x<height>"
+	INPUT_FILE Input = "INPUT_FILE" // Map file name
+)
+
+// Maximum waypoints placed, keeping the best order search small.
+const MAX_WAYPOINTS = 8
+
+// Maximum units placed for multi-agent searches, one per track color.
+const MAX_UNITS = 8
+
+// Longest a multi-agent search can run, as Conflict-Based Search never ends on some unsolvable layouts.
+const MULTI_TIMEOUT = 5 * time.Second
+
+// Weights painted by the TERRAIN tool, in the order the brush button cycles through them.
+var brushWeights = []int{solver.WEIGHT_GRASS, solver.WEIGHT_MUD, solver.WEIGHT_WATER}
+
+// SEARCH OPTIONS
+var (
+	neighborhoods      = []solver.Neighborhood{solver.NEIGHBORHOOD_4, solver.NEIGHBORHOOD_8, solver.NEIGHBORHOOD_8_NO_CORNERS, solver.NEIGHBORHOOD_HEX}
+	neighborhoodTitles = map[solver.Neighborhood]string{
+		solver.NEIGHBORHOOD_4:            "Movement: 4-way",
+		solver.NEIGHBORHOOD_8:            "Movement: 8-way",
+		solver.NEIGHBORHOOD_8_NO_CORNERS: "Movement: 8-way, no corners",
+		solver.NEIGHBORHOOD_HEX:          "Movement: hex",
+	}
+	// Heuristic picked with each neighbourhood, the tightest one that never overestimates its moves.
+	// Hex grids measure hex distances whatever the heuristic, Euclidean being the closest of the others.
+	neighborhoodHeuristics = map[solver.Neighborhood]solver.Heuristic{
+		solver.NEIGHBORHOOD_4:            solver.HEURISTIC_MANHATTAN,
+		solver.NEIGHBORHOOD_8:            solver.HEURISTIC_OCTILE,
+		solver.NEIGHBORHOOD_8_NO_CORNERS: solver.HEURISTIC_OCTILE,
+		solver.NEIGHBORHOOD_HEX:          solver.HEURISTIC_EUCLIDEAN,
+	}
+
+	heuristics      = []solver.Heuristic{solver.HEURISTIC_MANHATTAN, solver.HEURISTIC_OCTILE, solver.HEURISTIC_CHEBYSHEV, solver.HEURISTIC_EUCLIDEAN}
+	heuristicTitles = map[solver.Heuristic]string{
+		solver.HEURISTIC_MANHATTAN: "Heuristic: Manhattan",
+		solver.HEURISTIC_OCTILE:    "Heuristic: Octile",
+		solver.HEURISTIC_CHEBYSHEV: "Heuristic: Chebyshev",
+		solver.HEURISTIC_EUCLIDEAN: "Heuristic: Euclidean",
+	}
+
+	routeModes      = []solver.RouteMode{solver.ROUTE_IN_ORDER, solver.ROUTE_BEST_ORDER, solver.ROUTE_NEAREST}
+	routeModeTitles = map[solver.RouteMode]string{
+		solver.ROUTE_IN_ORDER:   "Route: in given order",
+		solver.ROUTE_BEST_ORDER: "Route: best order",
+		solver.ROUTE_NEAREST:    "Route: nearest goal",
+	}
+
+	planners      = []solver.Planner{solver.PLANNER_PRIORITIZED, solver.PLANNER_CBS}
+	plannerTitles = map[solver.Planner]string{
+		solver.PLANNER_PRIORITIZED: "Prioritized planning",
+		solver.PLANNER_CBS:         "Conflict-Based Search",
+	}
 )
 
 // CANVAS SIZES
@@ -76,6 +192,22 @@ const (
 	SIZE_L int = 110
 )
 
+// Smallest custom canvas size, in cells per side. The largest keeps cells a pixel wide on the current canvases.
+const MIN_SIZE int = 2
+
+// CANVAS LAYOUT
+const (
+	MAX_CANVASES = 6
+
+	// Area the canvases are laid out in, right of the left buttons and above the bottom ones
+	CANVAS_AREA_X = 175
+	CANVAS_AREA_W = 1200
+	CANVAS_AREA_H = SCREEN_HEIGHT - 100
+)
+
+// Solvers selected on new canvases, in the order they are added.
+var defaultSolvers = []string{"Dijkstra", "A*", "Jump Point Search", "Breadth-first search", "Greedy best-first", "Bidirectional A*"}
+
 type Game struct {
 	sc *ebiten.Image
 }
@@ -89,140 +221,239 @@ func (g *Game) Update() error {
 	buttonEraser.hover(posX, posY)
 	buttonFlagStart.hover(posX, posY)
 	buttonFlagEnd.hover(posX, posY)
+	buttonTerrain.hover(posX, posY)
+	buttonBrushWeight.hover(posX, posY)
 	buttonClearPath.hover(posX, posY)
 	buttonClearCanvas.hover(posX, posY)
 	buttonGenerateTerrain.hover(posX, posY)
 	buttonTerrainSizeS.hover(posX, posY)
 	buttonTerrainSizeM.hover(posX, posY)
 	buttonTerrainSizeL.hover(posX, posY)
+	buttonCustomSize.hover(posX, posY)
 	buttonPlay.hover(posX, posY)
+	buttonPause.hover(posX, posY)
+	buttonStep.hover(posX, posY)
 	buttonMsMinus.hover(posX, posY)
 	buttonMsPlus.hover(posX, posY)
 	buttonGithub.hover(posX, posY)
+	buttonNeighborhood.hover(posX, posY)
+	buttonHeuristic.hover(posX, posY)
+	buttonWeightMinus.hover(posX, posY)
+	buttonWeightPlus.hover(posX, posY)
+	buttonAgent.hover(posX, posY)
+	buttonSensorMinus.hover(posX, posY)
+	buttonSensorPlus.hover(posX, posY)
+	buttonWaypoint.hover(posX, posY)
+	buttonRouteMode.hover(posX, posY)
+	buttonUnit.hover(posX, posY)
+	buttonClusters.hover(posX, posY)
+	buttonCanvasMinus.hover(posX, posY)
+	buttonCanvasPlus.hover(posX, posY)
+	buttonMapFile.hover(posX, posY)
+	buttonSave.hover(posX, posY)
+	buttonLoad.hover(posX, posY)
+	buttonGreyTerrain.hover(posX, posY)
+	buttonRecord.hover(posX, posY)
+	for _, canvas := range canvases {
+		canvas.buttonPrevSolver.hover(posX, posY)
+		canvas.buttonNextSolver.hover(posX, posY)
+	}
 
 	// BUTTON SELECTION STATES
-	switch canvasSize {
-	case SIZE_S:
-		buttonTerrainSizeS.active = true
-		buttonTerrainSizeM.active = false
-		buttonTerrainSizeL.active = false
-	case SIZE_M:
-		buttonTerrainSizeS.active = false
-		buttonTerrainSizeM.active = true
-		buttonTerrainSizeL.active = false
-	case SIZE_L:
-		buttonTerrainSizeS.active = false
-		buttonTerrainSizeM.active = false
-		buttonTerrainSizeL.active = true
-	}
-
-	if canvasA.grid.Status == STATUS_PATHING || canvasB.grid.Status == STATUS_PATHING {
+	buttonTerrainSizeS.active = canvasRows == SIZE_S && canvasCols == SIZE_S
+	buttonTerrainSizeM.active = canvasRows == SIZE_M && canvasCols == SIZE_M
+	buttonTerrainSizeL.active = canvasRows == SIZE_L && canvasCols == SIZE_L
+	buttonCustomSize.active = activeInput == INPUT_SIZE
+	if activeInput == INPUT_SIZE {
+		buttonCustomSize.SetTitle(inputText + "_")
+	} else {
+		buttonCustomSize.SetTitle(fmt.Sprintf("%dx%d", canvasCols, canvasRows))
+	}
+	buttonMapFile.active = activeInput == INPUT_FILE
+	if activeInput == INPUT_FILE {
+		buttonMapFile.SetTitle(inputText + "_")
+	} else {
+		buttonMapFile.SetTitle("File: " + mapFile)
+	}
+
+	if anyPathing() {
 		buttonPlay.active = true
 		buttonPlay.title = "Stop"
+		buttonPause.active = paused
+		buttonPause.disabled = false
 
 		buttonPencil.disabled = true
 		buttonEraser.disabled = true
 		buttonFlagStart.disabled = true
 		buttonFlagEnd.disabled = true
+		buttonTerrain.disabled = true
+		buttonBrushWeight.disabled = true
 		buttonClearPath.disabled = true
 		buttonClearCanvas.disabled = true
 		buttonGenerateTerrain.disabled = true
 		buttonTerrainSizeS.disabled = true
 		buttonTerrainSizeM.disabled = true
 		buttonTerrainSizeL.disabled = true
-	} else if canvasA.grid.Status != STATUS_PATHING && canvasB.grid.Status != STATUS_PATHING {
+		buttonCustomSize.disabled = true
+		buttonNeighborhood.disabled = true
+		buttonHeuristic.disabled = true
+		buttonWeightMinus.disabled = true
+		buttonWeightPlus.disabled = true
+		buttonAgent.disabled = true
+		buttonSensorMinus.disabled = true
+		buttonSensorPlus.disabled = true
+		buttonWaypoint.disabled = true
+		buttonRouteMode.disabled = true
+		buttonUnit.disabled = true
+		buttonCanvasMinus.disabled = true
+		buttonCanvasPlus.disabled = true
+		buttonMapFile.disabled = true
+		buttonSave.disabled = true
+		buttonLoad.disabled = true
+		buttonGreyTerrain.disabled = true
+		buttonRecord.disabled = true
+		for _, canvas := range canvases {
+			canvas.buttonPrevSolver.disabled = true
+			canvas.buttonNextSolver.disabled = true
+		}
+	} else {
 		buttonPlay.active = false
 		buttonPlay.title = "Play"
+		buttonPause.active = false
+		buttonPause.disabled = true
 
 		buttonPencil.disabled = false
 		buttonEraser.disabled = false
 		buttonFlagStart.disabled = false
 		buttonFlagEnd.disabled = false
+		buttonTerrain.disabled = false
+		buttonBrushWeight.disabled = false
 		buttonClearPath.disabled = false
 		buttonClearCanvas.disabled = false
 		buttonGenerateTerrain.disabled = false
 		buttonTerrainSizeS.disabled = false
 		buttonTerrainSizeM.disabled = false
 		buttonTerrainSizeL.disabled = false
+		buttonCustomSize.disabled = false
+		buttonNeighborhood.disabled = false
+		buttonHeuristic.disabled = false
+		buttonWeightMinus.disabled = false
+		buttonWeightPlus.disabled = false
+		buttonAgent.disabled = false
+		buttonSensorMinus.disabled = false
+		buttonSensorPlus.disabled = false
+		buttonWaypoint.disabled = false
+		buttonRouteMode.disabled = false
+		buttonUnit.disabled = false
+		buttonCanvasMinus.disabled = false
+		buttonCanvasPlus.disabled = false
+		buttonMapFile.disabled = false
+		buttonSave.disabled = false
+		buttonLoad.disabled = false
+		buttonGreyTerrain.disabled = false
+		buttonRecord.disabled = false
+		for _, canvas := range canvases {
+			canvas.buttonPrevSolver.disabled = false
+			canvas.buttonNextSolver.disabled = false
+		}
+	}
+
+	// TEXT INPUT
+	if activeInput != INPUT_NONE {
+		for _, char := range ebiten.AppendInputChars(nil) {
+			if activeInput == INPUT_SIZE && (char >= '0' && char <= '9' || char == 'x') && len(inputText) < 7 ||
+				activeInput == INPUT_FILE && unicode.IsPrint(char) && len(inputText) < 40 {
+				inputText += string(char)
+			}
+		}
+
+		if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(inputText) > 0 {
+			_, last := utf8.DecodeLastRuneInString(inputText)
+			inputText = inputText[:len(inputText)-last]
+		} else if inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeyNumpadEnter) {
+			switch activeInput {
+			case INPUT_SIZE:
+				if rows, cols, ok := parseSize(inputText); ok {
+					setGridSize(rows, cols)
+				}
+			case INPUT_FILE:
+				if inputText != "" {
+					mapFile = inputText
+				}
+			}
+			activeInput = INPUT_NONE
+		} else if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+			activeInput = INPUT_NONE
+		}
+	}
+
+	// Maps dropped on the window are loaded like the Load button does
+	if dropped := ebiten.DroppedFiles(); dropped != nil && !anyPathing() {
+		if entries, err := fs.ReadDir(dropped, "."); err == nil && len(entries) > 0 {
+			loadMap(dropped, entries[0].Name())
+		}
 	}
 
 	// BUTTON CLICKS
 	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		activeInput = INPUT_NONE // Clicking anywhere else cancels the typed input
+
 		if buttonPencil.hovered {
-			activeTool = PENCIL
-			buttonPencil.active = true
-			buttonEraser.active = false
-			buttonFlagStart.active = false
-			buttonFlagEnd.active = false
+			selectTool(PENCIL)
 		} else if buttonEraser.hovered {
-			activeTool = ERASER
-			buttonPencil.active = false
-			buttonEraser.active = true
-			buttonFlagStart.active = false
-			buttonFlagEnd.active = false
+			selectTool(ERASER)
 		} else if buttonFlagStart.hovered {
-			activeTool = FLAG_START
-			buttonPencil.active = false
-			buttonEraser.active = false
-			buttonFlagStart.active = true
-			buttonFlagEnd.active = false
+			selectTool(FLAG_START)
 		} else if buttonFlagEnd.hovered {
-			activeTool = FLAG_END
-			buttonPencil.active = false
-			buttonEraser.active = false
-			buttonFlagStart.active = false
-			buttonFlagEnd.active = true
+			selectTool(FLAG_END)
+		} else if buttonTerrain.hovered {
+			selectTool(TERRAIN)
+		} else if buttonBrushWeight.hovered {
+			brushWeight = next(brushWeights, brushWeight)
+			buttonBrushWeight.SetTitle(fmt.Sprintf("x%d", brushWeight))
+			selectTool(TERRAIN)
 		} else if buttonClearPath.hovered {
-			if canvasA.grid.Status != STATUS_PATHING && canvasB.grid.Status != STATUS_PATHING {
-				canvasA.grid.Restart(true)
-				canvasB.grid.Restart(true)
+			if !anyPathing() {
+				for _, canvas := range canvases {
+					canvas.grid.Restart(true)
+				}
 			}
 		} else if buttonClearCanvas.hovered {
-			if canvasA.grid.Status != STATUS_PATHING && canvasB.grid.Status != STATUS_PATHING {
-				canvasA.grid.Restart(false)
-				canvasB.grid.Restart(false)
+			if !anyPathing() {
+				for _, canvas := range canvases {
+					canvas.grid.Restart(false)
+				}
 			}
 		} else if buttonGenerateTerrain.hovered {
-			if canvasA.grid.Status != STATUS_PATHING && canvasB.grid.Status != STATUS_PATHING {
-				canvasA.grid.Restart(false)
-				canvasB.grid.Restart(false)
-				for i, row := range canvasA.grid.Cells {
-					for j := range row {
-						nodeA := &canvasA.grid.Cells[i][j]
-						nodeB := &canvasB.grid.Cells[i][j]
-						if !canvasA.grid.Start.Coord.Eq(pair.New(i, j)) && !canvasA.grid.End.Coord.Eq(pair.New(i, j)) {
-							isWall := rand.Intn(100) < 20
-							nodeA.IsWall = isWall
-							nodeB.IsWall = isWall
-						}
-					}
-				}
+			if !anyPathing() {
+				generateTerrain()
 			}
 		} else if buttonTerrainSizeS.hovered {
-			canvasA.SetGrid(NewGrid(SIZE_S, pair.New(SIZE_S-1, 0), pair.New(0, SIZE_S-1)))
-			canvasB.SetGrid(NewGrid(SIZE_S, pair.New(SIZE_S-1, 0), pair.New(0, SIZE_S-1)))
+			setGridSize(SIZE_S, SIZE_S)
 		} else if buttonTerrainSizeM.hovered {
-			canvasA.SetGrid(NewGrid(SIZE_M, pair.New(SIZE_M-1, 0), pair.New(0, SIZE_M-1)))
-			canvasB.SetGrid(NewGrid(SIZE_M, pair.New(SIZE_M-1, 0), pair.New(0, SIZE_M-1)))
+			setGridSize(SIZE_M, SIZE_M)
 		} else if buttonTerrainSizeL.hovered {
-			canvasA.SetGrid(NewGrid(SIZE_L, pair.New(SIZE_L-1, 0), pair.New(0, SIZE_L-1)))
-			canvasB.SetGrid(NewGrid(SIZE_L, pair.New(SIZE_L-1, 0), pair.New(0, SIZE_L-1)))
+			setGridSize(SIZE_L, SIZE_L)
+		} else if buttonCustomSize.hovered {
+			activeInput = INPUT_SIZE
+			inputText = ""
 		} else if buttonPlay.hovered {
-			if canvasA.grid.Status != STATUS_PATHING && canvasB.grid.Status != STATUS_PATHING {
-				canvasA.grid.Restart(true)
-				canvasB.grid.Restart(true)
-				go canvasA.grid.DoDijkstra()
-				go canvasB.grid.DoAStar()
-				stopSignal = make(chan struct{})
+			if !anyPathing() {
+				startSearches()
 			} else {
-				func() {
-					close(stopSignal)
-					for canvasA.grid.Status == STATUS_PATHING || canvasB.grid.Status == STATUS_PATHING {
-						// Wait until both algorithms have stopped to prevent closing the closed channel.
-						// Could happen in high cooldown setting and/or when pressing the stop button multiple times.
-					}
-				}()
+				for _, canvas := range canvases {
+					canvas.StopSearch()
+				}
+			}
+		} else if buttonPause.hovered {
+			paused = !paused
+			lastStepTime = time.Now()
+		} else if buttonStep.hovered {
+			if !anyPathing() {
+				startSearches()
+				paused = true
 			}
+			stepSearches()
 		} else if buttonMsMinus.hovered {
 			if iterationCooldownMS <= 10 {
 				if iterationCooldownMS > 0 {
@@ -245,49 +476,503 @@ func (g *Game) Update() error {
 			} else if iterationCooldownMS >= 100 && iterationCooldownMS < 1000 {
 				iterationCooldownMS += 100
 			}
+		} else if canvas, delta := hoveredSolverButton(); canvas != nil {
+			canvas.CycleSolver(delta)
+		} else if buttonNeighborhood.hovered {
+			neighborhood = next(neighborhoods, neighborhood)
+			heuristic = neighborhoodHeuristics[neighborhood]
+			buttonNeighborhood.SetTitle(neighborhoodTitles[neighborhood])
+			buttonHeuristic.SetTitle(heuristicTitles[heuristic])
+			for _, canvas := range canvases {
+				canvas.grid.Neighborhood = neighborhood // Hex grids are drawn and picked differently right away
+			}
+		} else if buttonHeuristic.hovered {
+			heuristic = next(heuristics, heuristic)
+			buttonHeuristic.SetTitle(heuristicTitles[heuristic])
+		} else if buttonWeightMinus.hovered {
+			if heuristicWeight > 1 {
+				heuristicWeight -= 0.25
+			}
+		} else if buttonWeightPlus.hovered {
+			if heuristicWeight < 5 {
+				heuristicWeight += 0.25
+			}
+		} else if buttonAgent.hovered {
+			agentMode = !agentMode
+			buttonAgent.active = agentMode
+		} else if buttonSensorMinus.hovered {
+			if sensorRadius > 1 {
+				sensorRadius--
+			}
+		} else if buttonSensorPlus.hovered {
+			if sensorRadius < 20 {
+				sensorRadius++
+			}
+		} else if buttonWaypoint.hovered {
+			selectTool(WAYPOINT)
+		} else if buttonRouteMode.hovered {
+			routeMode = next(routeModes, routeMode)
+			buttonRouteMode.SetTitle(routeModeTitles[routeMode])
+		} else if buttonUnit.hovered {
+			selectTool(UNIT)
+		} else if buttonClusters.hovered {
+			showClusters = !showClusters
+			buttonClusters.active = showClusters
+		} else if buttonCanvasMinus.hovered {
+			if len(canvases) > 1 && !layoutCanvases(len(canvases)-1) {
+				mapMessage = fmt.Sprintf("%dx%d is too big for %d canvases", canvasCols, canvasRows, len(canvases)-1)
+			}
+		} else if buttonCanvasPlus.hovered {
+			if len(canvases) < MAX_CANVASES && !layoutCanvases(len(canvases)+1) {
+				mapMessage = fmt.Sprintf("%dx%d is too big for %d canvases", canvasCols, canvasRows, len(canvases)+1)
+			}
+		} else if buttonMapFile.hovered {
+			activeInput = INPUT_FILE
+			inputText = mapFile
+		} else if buttonSave.hovered {
+			saveMap(mapFile)
+		} else if buttonLoad.hovered {
+			loadMap(os.DirFS("."), mapFile)
+		} else if buttonGreyTerrain.hovered {
+			greyTerrain = !greyTerrain
+			buttonGreyTerrain.active = greyTerrain
+		} else if buttonRecord.hovered {
+			recordMode = !recordMode
+			buttonRecord.active = recordMode
 		} else if buttonGithub.hovered {
 			browser.OpenURL("https://github.com/keelus/pathfinding")
 		}
 	}
 
 	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) &&
-		canvasA.grid.Status != STATUS_PATHING && canvasB.grid.Status != STATUS_PATHING {
-		if i, j, canvas := mousePosCoords(&canvasA, &canvasB, posX, posY); canvas != nil {
+		!anyPathing() {
+		if i, j, canvas := mousePosCoords(canvases, posX, posY); canvas != nil {
 			switch activeTool {
-			case PENCIL, ERASER:
+			case PENCIL, ERASER, TERRAIN:
 				drawing = true
 			case FLAG_START:
-				if !canvas.grid.Cells[i][j].IsWall && !canvas.grid.Start.Coord.Eq(pair.New(i, j)) {
-					canvasA.grid.Start = &canvasA.grid.Cells[i][j]
-					canvasB.grid.Start = &canvasB.grid.Cells[i][j]
+				if !canvas.grid.Cells[i][j].IsWall && !canvas.grid.IsFlag(pair.New(i, j)) {
+					for _, canvas := range canvases {
+						canvas.grid.Start = &canvas.grid.Cells[i][j]
+					}
 				}
 			case FLAG_END:
-				if !canvas.grid.Cells[i][j].IsWall && !canvas.grid.Start.Coord.Eq(pair.New(i, j)) {
-					canvasA.grid.End = &canvasA.grid.Cells[i][j]
-					canvasB.grid.End = &canvasB.grid.Cells[i][j]
+				if !canvas.grid.Cells[i][j].IsWall && !canvas.grid.IsFlag(pair.New(i, j)) {
+					for _, canvas := range canvases {
+						canvas.grid.End = &canvas.grid.Cells[i][j]
+					}
+				}
+			case WAYPOINT:
+				if canvas.grid.IsWaypoint(pair.New(i, j)) || len(canvas.grid.Waypoints) < MAX_WAYPOINTS {
+					for _, canvas := range canvases {
+						canvas.grid.ToggleWaypoint(pair.New(i, j))
+					}
+				}
+			case UNIT:
+				if k, _ := canvas.grid.TaskAt(pair.New(i, j)); k != -1 {
+					for _, canvas := range canvases {
+						canvas.grid.RemoveTask(pair.New(i, j))
+					}
+					placingUnit = false
+				} else if placingUnit {
+					for _, canvas := range canvases {
+						canvas.grid.AddTask(unitStart, pair.New(i, j))
+					}
+					placingUnit = false
+				} else if !canvas.grid.Cells[i][j].IsWall && !canvas.grid.IsFlag(pair.New(i, j)) && len(canvas.grid.Tasks) < MAX_UNITS {
+					unitStart = pair.New(i, j)
+					placingUnit = true
 				}
 			}
 		}
 	}
 
 	if inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) {
-		if activeTool == PENCIL || activeTool == ERASER {
+		if activeTool == PENCIL || activeTool == ERASER || activeTool == TERRAIN {
 			drawing = false
 		}
 	}
 
 	if drawing {
-		if i, j, canvas := mousePosCoords(&canvasA, &canvasB, posX, posY); canvas != nil {
-			if !canvas.grid.Start.Coord.Eq(pair.New(i, j)) && !canvas.grid.End.Coord.Eq(pair.New(i, j)) {
-				canvasA.grid.Cells[i][j].IsWall = activeTool == PENCIL
-				canvasB.grid.Cells[i][j].IsWall = activeTool == PENCIL
+		if i, j, canvas := mousePosCoords(canvases, posX, posY); canvas != nil {
+			if !canvas.grid.IsFlag(pair.New(i, j)) {
+				weight := solver.BASE_WEIGHT
+				if activeTool == TERRAIN {
+					weight = brushWeight
+				}
+
+				for _, canvas := range canvases {
+					node := &canvas.grid.Cells[i][j]
+					if node.IsWall == (activeTool == PENCIL) && node.Weight == weight {
+						continue
+					}
+
+					node.IsWall = activeTool == PENCIL
+					node.Weight = weight
+					if canvas.CellChanged(node) {
+						paused = false
+						lastStepTime = time.Now()
+					}
+				}
 			}
 		}
 	}
 
+	for _, canvas := range canvases {
+		canvas.CheckPlan()
+	}
+	if !paused {
+		advanceSearches()
+	}
+
+	if recorder != nil && !anyPathing() {
+		saveRecording()
+	}
+	select {
+	case message := <-recordSaved:
+		mapMessage = message
+	default:
+	}
+
 	return nil
 }
 
+// selectTool makes tool the active one and highlights its button.
+func selectTool(tool Tool) {
+	activeTool = tool
+	buttonPencil.active = tool == PENCIL
+	buttonEraser.active = tool == ERASER
+	buttonFlagStart.active = tool == FLAG_START
+	buttonFlagEnd.active = tool == FLAG_END
+	buttonTerrain.active = tool == TERRAIN
+	buttonWaypoint.active = tool == WAYPOINT
+	buttonUnit.active = tool == UNIT
+	placingUnit = false
+}
+
+// generateTerrain clears every canvas and walls a random fifth of the cells, the same ones on all of them.
+func generateTerrain() {
+	for _, canvas := range canvases {
+		canvas.grid.Restart(false)
+	}
+	for i, row := range canvases[0].grid.Cells {
+		for j := range row {
+			if !canvases[0].grid.IsFlag(pair.New(i, j)) {
+				isWall := rand.Intn(100) < 20
+				for _, canvas := range canvases {
+					canvas.grid.Cells[i][j].IsWall = isWall
+				}
+			}
+		}
+	}
+}
+
+// setGridSize gives every canvas an empty grid of rows*cols cells, with the flags in opposite corners.
+func setGridSize(rows, cols int) {
+	for _, canvas := range canvases {
+		canvas.SetGrid(solver.NewGrid(rows, cols, pair.New(rows-1, 0), pair.New(0, cols-1)))
+	}
+}
+
+// parseSize parses a custom size typed as "<width>x<height>", or a single number for a square grid.
+func parseSize(input string) (rows, cols int, ok bool) {
+	width, height, found := strings.Cut(input, "x")
+	if !found {
+		height = width
+	}
+
+	cols, errW := strconv.Atoi(width)
+	rows, errH := strconv.Atoi(height)
+	maxCols, maxRows := canvases[0].w/2, canvases[0].h/2 // Cells of one pixel, and the pixel between them
+	if errW != nil || errH != nil || rows < MIN_SIZE || rows > maxRows || cols < MIN_SIZE || cols > maxCols {
+		return 0, 0, false
+	}
+	return rows, cols, true
+}
+
+// saveMap writes the layout and flags shared by the canvases to the map file at path.
+// Paths ending in .map are written as Moving AI maps, which only keep the walls, and paths ending in .png
+// get the image of every canvas instead.
+func saveMap(path string) {
+	if filepath.Ext(path) == ".png" {
+		savePNG(path)
+		return
+	}
+
+	write := solver.WriteMap
+	if filepath.Ext(path) == ".map" {
+		write = solver.WriteMovingAIMap
+	}
+
+	file, err := os.Create(path)
+	if err == nil {
+		err = write(file, &canvases[0].grid)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	if err != nil {
+		mapMessage = "Save failed: " + err.Error()
+	} else {
+		mapMessage = "Saved " + path
+	}
+}
+
+// savePNG writes the image of the canvas to path, or with several canvases, of each one to path with its number
+// before the extension.
+func savePNG(path string) {
+	var saved []string
+	for k, canvas := range canvases {
+		name := path
+		if len(canvases) > 1 {
+			name = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ".png"), k+1, ".png")
+		}
+
+		file, err := os.Create(name)
+		if err == nil {
+			err = png.Encode(file, canvas.Image())
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			mapMessage = "Save failed: " + err.Error()
+			return
+		}
+		saved = append(saved, name)
+	}
+
+	mapMessage = "Saved " + strings.Join(saved, ", ")
+}
+
+// loadMap gives every canvas the grid of the map file name in fsys, if it can be read and fits them.
+// Names ending in .map are read as Moving AI maps, switching to the moves and heuristic of their benchmarks.
+// The error returned is also shown as the map message.
+func loadMap(fsys fs.FS, name string) error {
+	file, err := fsys.Open(name)
+	if err != nil {
+		mapMessage = "Load failed: " + err.Error()
+		return err
+	}
+	defer file.Close()
+
+	read := solver.ReadMap
+	switch filepath.Ext(name) {
+	case ".map":
+		read = solver.ReadMovingAIMap
+	case ".png":
+		read = func(r io.Reader) (solver.Grid, error) {
+			img, _, err := image.Decode(r)
+			if err != nil {
+				return solver.Grid{}, err
+			}
+			return solver.GridFromImage(img, canvasRows, canvasCols, greyTerrain)
+		}
+	}
+
+	grid, err := read(file)
+	if err == nil && (len(grid.Cells) > canvases[0].h/2 || len(grid.Cells[0]) > canvases[0].w/2) {
+		err = fmt.Errorf("%dx%d is too big for the canvases", len(grid.Cells[0]), len(grid.Cells))
+	}
+	if err != nil {
+		mapMessage = "Load failed: " + err.Error()
+		return err
+	}
+
+	if filepath.Ext(name) == ".map" {
+		neighborhood, heuristic = grid.Neighborhood, grid.Heuristic
+		buttonNeighborhood.SetTitle(neighborhoodTitles[neighborhood])
+		buttonHeuristic.SetTitle(heuristicTitles[heuristic])
+	}
+
+	for _, canvas := range canvases {
+		canvas.SetGrid(grid.Clone())
+	}
+	mapMessage = "Loaded " + name
+	return nil
+}
+
+// layoutCanvases shows count canvases in a grid, keeping the solvers of the ones already shown.
+// New canvases get the next default solver and a copy of the layout and flags of the first one.
+// It reports false, leaving the canvases as they are, if the grid would not fit the smaller canvases.
+func layoutCanvases(count int) bool {
+	cols := min(count, 3)
+	if count == 4 {
+		cols = 2
+	}
+	rows := (count + cols - 1) / cols
+
+	// Square canvases, leaving room for the solver selector above and the stats lines below
+	colW, rowH := CANVAS_AREA_W/cols, CANVAS_AREA_H/rows
+	side := min(colW-50, rowH-95)
+	if canvasRows > side/2 || canvasCols > side/2 { // Cells of one pixel, and the pixel between them
+		return false
+	}
+
+	resized := make([]*Canvas, count)
+	for k := range resized {
+		x := CANVAS_AREA_X + k%cols*colW + (colW-side)/2
+		y := k/cols*rowH + 40
+
+		canvas := NewCanvas(side, side, float64(x), float64(y), defaultSolvers[k])
+		canvas.planner = planners[k%len(planners)]
+		if k < len(canvases) {
+			canvas.solverName = canvases[k].solverName
+			canvas.planner = canvases[k].planner
+		}
+		resized[k] = &canvas
+	}
+
+	if len(canvases) > 0 {
+		for _, canvas := range resized {
+			canvas.SetGrid(canvases[0].grid.Clone())
+		}
+	}
+	canvases = resized
+	return true
+}
+
+// anyPathing reports whether a search is running on any canvas.
+func anyPathing() bool {
+	for _, canvas := range canvases {
+		if canvas.grid.Status == solver.STATUS_PATHING {
+			return true
+		}
+	}
+	return false
+}
+
+// hoveredSolverButton returns the canvas whose solver selector button is hovered, and the direction it cycles in.
+func hoveredSolverButton() (*Canvas, int) {
+	for _, canvas := range canvases {
+		if canvas.buttonPrevSolver.hovered {
+			return canvas, -1
+		} else if canvas.buttonNextSolver.hovered {
+			return canvas, 1
+		}
+	}
+	return nil, 0
+}
+
+// next returns the element following current in list, wrapping around at the end.
+func next[T comparable](list []T, current T) T {
+	for i, elem := range list {
+		if elem == current {
+			return list[(i+1)%len(list)]
+		}
+	}
+	return list[0]
+}
+
+// cycle returns the element delta positions away from current in list, wrapping around at both ends.
+func cycle[T comparable](list []T, current T, delta int) T {
+	index := 0
+	for i, elem := range list {
+		if elem == current {
+			index = i
+		}
+	}
+	return list[((index+delta)%len(list)+len(list))%len(list)]
+}
+
+// startSearches restarts every canvas with its selected solver, walking an agent in agent mode.
+func startSearches() {
+	for _, canvas := range canvases {
+		if agentMode {
+			canvas.StartAgent(sensorRadius)
+		} else {
+			canvas.StartSearch()
+		}
+	}
+
+	recorder = nil
+	if recordMode {
+		recorder = NewRecorder()
+		recorder.Capture(canvases)
+	}
+	paused = false
+	lastStepTime = time.Now()
+}
+
+// advanceSearches runs as many solver iterations as the cooldown allows since the last ones.
+func advanceSearches() {
+	steps := MAX_STEPS_PER_FRAME
+	if iterationCooldownMS > 0 {
+		cooldown := time.Duration(iterationCooldownMS) * time.Millisecond
+		steps = int(time.Since(lastStepTime) / cooldown)
+		lastStepTime = lastStepTime.Add(time.Duration(steps) * cooldown)
+		if steps > MAX_STEPS_PER_FRAME {
+			steps = MAX_STEPS_PER_FRAME
+			lastStepTime = time.Now()
+		}
+	}
+
+	for i := 0; i < steps; i++ {
+		stepSearches()
+	}
+}
+
+// stepSearches runs one iteration of the search on every canvas, capturing them if the solve is recorded.
+func stepSearches() {
+	for _, canvas := range canvases {
+		canvas.StepSearch()
+	}
+	if recorder != nil {
+		recorder.Step(canvases)
+	}
+}
+
+// saveRecording captures the finished solve and saves the recording to RECORD_FILE in the background,
+// as encoding it takes a while.
+func saveRecording() {
+	recorder.Capture(canvases)
+	mapMessage = "Saving " + RECORD_FILE + "..."
+
+	go func(r *Recorder) {
+		if err := r.Save(RECORD_FILE); err != nil {
+			recordSaved <- "Recording failed: " + err.Error()
+		} else {
+			recordSaved <- fmt.Sprintf("Saved %s (%d frames)", RECORD_FILE, r.Frames())
+		}
+	}(recorder)
+	recorder = nil
+}
+
+// recordHeadless records solving the map file layout, or random terrain if it is empty, with each solver
+// side by side, and saves the recording to path without opening a window.
+func recordHeadless(path, layout string, solverNames []string) error {
+	if len(solverNames) < 1 || len(solverNames) > MAX_CANVASES {
+		return fmt.Errorf("expected 1 to %d solvers, got %d", MAX_CANVASES, len(solverNames))
+	}
+	for _, name := range solverNames {
+		if solver.New(name) == nil {
+			return fmt.Errorf("unknown solver %q", name)
+		}
+	}
+
+	layoutCanvases(len(solverNames))
+	for k, canvas := range canvases {
+		canvas.solverName = solverNames[k]
+	}
+
+	setGridSize(SIZE_M, SIZE_M)
+	if layout == "" {
+		generateTerrain()
+	} else if err := loadMap(os.DirFS(filepath.Dir(layout)), filepath.Base(layout)); err != nil {
+		return err
+	}
+
+	recordMode = true
+	startSearches()
+	for anyPathing() {
+		stepSearches()
+	}
+	recorder.Capture(canvases)
+
+	return recorder.Save(path)
+}
+
 func (g *Game) Draw(screen *ebiten.Image) {
 	if g.sc == nil {
 		g.sc = screen
@@ -298,35 +983,69 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	buttonEraser.Draw(screen)
 	buttonFlagStart.Draw(screen)
 	buttonFlagEnd.Draw(screen)
+	buttonTerrain.Draw(screen)
+	buttonBrushWeight.Draw(screen)
 	buttonClearPath.Draw(screen)
 	buttonClearCanvas.Draw(screen)
 	buttonGenerateTerrain.Draw(screen)
 	buttonTerrainSizeS.Draw(screen)
 	buttonTerrainSizeM.Draw(screen)
 	buttonTerrainSizeL.Draw(screen)
+	buttonCustomSize.Draw(screen)
 	buttonPlay.Draw(screen)
+	buttonPause.Draw(screen)
+	buttonStep.Draw(screen)
 	buttonMsMinus.Draw(screen)
 	buttonMsPlus.Draw(screen)
 	buttonGithub.Draw(screen)
+	buttonNeighborhood.Draw(screen)
+	buttonHeuristic.Draw(screen)
+	buttonWeightMinus.Draw(screen)
+	buttonWeightPlus.Draw(screen)
+	buttonAgent.Draw(screen)
+	buttonSensorMinus.Draw(screen)
+	buttonSensorPlus.Draw(screen)
+	buttonWaypoint.Draw(screen)
+	buttonRouteMode.Draw(screen)
+	buttonUnit.Draw(screen)
+	buttonClusters.Draw(screen)
+	buttonCanvasMinus.Draw(screen)
+	buttonCanvasPlus.Draw(screen)
+	buttonMapFile.Draw(screen)
+	buttonSave.Draw(screen)
+	buttonLoad.Draw(screen)
+	buttonGreyTerrain.Draw(screen)
+	buttonRecord.Draw(screen)
 
 	// LEFT TEXTS DRAWING
 	textColor := color.RGBA{255, 255, 255, 255}
-	if canvasA.grid.Status == STATUS_PATHING || canvasB.grid.Status == STATUS_PATHING {
+	if anyPathing() {
 		textColor = color.RGBA{0x4b, 0x4b, 0x4b, 255}
 	}
 
 	text.Draw(screen, categoryTools, mononokiFFace, 15, 45, textColor)
 	text.Draw(screen, categoryClear, mononokiFFace, 15, 240, textColor)
 	text.Draw(screen, "Canvas size", mononokiFFace, 15, 360, textColor)
+	text.Draw(screen, categoryCanvases, mononokiFFace, 15, 565, textColor)
+	text.Draw(screen, fmt.Sprintf("%d", len(canvases)), mononokiFFace, 95, 595, textColor)
 	text.Draw(screen, categoryCooldown, mononokiFFace, 15, SCREEN_HEIGHT-115, color.White)
 	text.Draw(screen, fmt.Sprintf("%dms", iterationCooldownMS), mononokiFFace, 80, SCREEN_HEIGHT-85, color.White)
 
+	// BOTTOM TEXTS DRAWING
+	text.Draw(screen, fmt.Sprintf("Weight: %.2f", heuristicWeight), mononokiFFace, 785, SCREEN_HEIGHT-77, textColor)
+	text.Draw(screen, fmt.Sprintf("Sensor: %d", sensorRadius), mononokiFFace, 340, SCREEN_HEIGHT-32, textColor)
+	text.Draw(screen, mapMessage, mononokiFFaceSmall, 960, SCREEN_HEIGHT-6, textColor)
+
 	// CANVAS DRAWING
-	canvasA.Draw(screen)
-	canvasB.Draw(screen)
+	for _, canvas := range canvases {
+		canvas.Draw(screen)
+		if placingUnit {
+			canvas.drawUnitStart(screen, unitStart)
+		}
+	}
 
 	iconGithubOp := &ebiten.DrawImageOptions{}
-	iconGithubOp.GeoM.Translate(SCREEN_WIDTH/2-25, 10)
+	iconGithubOp.GeoM.Translate(10, SCREEN_HEIGHT-35)
 	screen.DrawImage(iconGithub, iconGithubOp)
 }
 
@@ -338,6 +1057,21 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 var assets embed.FS
 
 func main() {
+	scenarioFile := flag.String("scen", "", "run the Moving AI scenario file at this path without a window, and exit")
+	scenarioMap := flag.String("map", "", "Moving AI map of the scenarios, instead of the one each scenario names")
+	scenarioSolver := flag.String("solver", "A*", "solver the scenarios are searched with")
+	recordFile := flag.String("gif", "", "record solving the layout to this GIF file without a window, and exit")
+	recordLayout := flag.String("layout", "", "map file recorded with -gif, instead of random terrain")
+	recordSolvers := flag.String("solvers", "Dijkstra,A*", "comma separated solvers recorded side by side with -gif")
+	flag.Parse()
+
+	if *scenarioFile != "" {
+		if !runScenarios(*scenarioFile, *scenarioMap, *scenarioSolver) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	ebiten.SetWindowSize(SCREEN_WIDTH, SCREEN_HEIGHT)
 	ebiten.SetWindowTitle("pathfinding - keelus")
 	ebiten.SetWindowIcon([]image.Image{loadImage("assets/icons/greenFlag.png")})
@@ -346,20 +1080,41 @@ func main() {
 	mononokiFFaceSmall = getFont("assets/fonts/mononoki.ttf", 14)
 
 	activeTool = PENCIL
+	brushWeight = solver.WEIGHT_GRASS
 
 	iterationCooldownMS = 10
 
-	// CREATE CANVAS & SET GRID (default: Medium)
-	canvasA = NewCanvas(550, 550, 200, 40, "Dijkstra")
-	canvasB = NewCanvas(550, 550, 800, 40, "A*")
-	canvasA.SetGrid(NewGrid(SIZE_M, pair.New(SIZE_M-1, 0), pair.New(0, SIZE_M-1)))
-	canvasB.SetGrid(NewGrid(SIZE_M, pair.New(SIZE_M-1, 0), pair.New(0, SIZE_M-1)))
+	neighborhood = solver.NEIGHBORHOOD_4
+	heuristic = solver.HEURISTIC_MANHATTAN
+	heuristicWeight = 2
+
+	sensorRadius = 5
+
+	routeMode = solver.ROUTE_IN_ORDER
+
+	activeInput = INPUT_NONE
+	mapFile = "map.txt"
+	recordSaved = make(chan string, 1)
+
+	if *recordFile != "" {
+		if err := recordHeadless(*recordFile, *recordLayout, strings.Split(*recordSolvers, ",")); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// CREATE CANVASES & SET GRID (default: two canvases, Medium)
+	layoutCanvases(2)
+	setGridSize(SIZE_M, SIZE_M)
 
 	// LEFT BUTTONS
-	buttonPencil = NewButton(50, 50, 50, 55, "P", true, getImage("assets/icons/pencil.png"), mononokiFFace)
-	buttonEraser = NewButton(50, 50, 100, 55, "E", false, getImage("assets/icons/eraser.png"), mononokiFFace)
-	buttonFlagStart = NewButton(50, 50, 50, 105, "F1", false, getImage("assets/icons/greenFlag.png"), mononokiFFace)
-	buttonFlagEnd = NewButton(50, 50, 100, 105, "F2", false, getImage("assets/icons/redFlag.png"), mononokiFFace)
+	buttonPencil = NewButton(50, 50, 25, 55, "P", true, getImage("assets/icons/pencil.png"), mononokiFFace)
+	buttonEraser = NewButton(50, 50, 75, 55, "E", false, getImage("assets/icons/eraser.png"), mononokiFFace)
+	buttonTerrain = NewButton(50, 50, 125, 55, "T", false, nil, mononokiFFace)
+	buttonFlagStart = NewButton(50, 50, 25, 105, "F1", false, getImage("assets/icons/greenFlag.png"), mononokiFFace)
+	buttonFlagEnd = NewButton(50, 50, 75, 105, "F2", false, getImage("assets/icons/redFlag.png"), mononokiFFace)
+	buttonBrushWeight = NewButton(50, 50, 125, 105, fmt.Sprintf("x%d", brushWeight), false, nil, mononokiFFace)
 
 	buttonClearPath = NewButton(150, 40, 25, 250, "Clear path", false, nil, mononokiFFace)
 	buttonClearCanvas = NewButton(150, 40, 25, 290, "Clear canvas", false, nil, mononokiFFace)
@@ -369,20 +1124,45 @@ func main() {
 	buttonTerrainSizeS = NewButton(40, 40, 25, 370, "S", false, nil, mononokiFFace)
 	buttonTerrainSizeM = NewButton(40, 40, 80, 370, "M", false, nil, mononokiFFace)
 	buttonTerrainSizeL = NewButton(40, 40, 135, 370, "L", false, nil, mononokiFFace)
+	buttonCustomSize = NewButton(150, 30, 25, 415, "", false, nil, mononokiFFace)
+
+	buttonPlay = NewButton(150, 40, 25, 465, "Play", false, nil, mononokiFFace)
+	buttonPause = NewButton(70, 30, 25, 510, "Pause", false, nil, mononokiFFace)
+	buttonStep = NewButton(70, 30, 105, 510, "Step", false, nil, mononokiFFace)
 
-	buttonPlay = NewButton(150, 40, 25, 460, "Play", false, nil, mononokiFFace)
+	buttonCanvasMinus = NewButton(30, 30, 25, 575, "-", false, nil, mononokiFFace)
+	buttonCanvasPlus = NewButton(30, 30, 145, 575, "+", false, nil, mononokiFFace)
 
 	buttonMsMinus = NewButton(30, 30, 25, SCREEN_HEIGHT-105, "-", false, nil, mononokiFFace)
 	buttonMsPlus = NewButton(30, 30, 145, SCREEN_HEIGHT-105, "+", false, nil, mononokiFFace)
 
-	// buttonGithub = NewButton(180, 30, (200-150)/2, SCREEN_HEIGHT-35, "   /keelus/pathfinding", false, nil, mononokiFFaceSmall)
-	buttonGithub = NewButton(190, 30, SCREEN_WIDTH/2-30, 5, "    /keelus/pathfinding", false, nil, mononokiFFaceSmall)
+	// Bottom left, as canvas selectors can take the whole top row
+	buttonGithub = NewButton(190, 30, 5, SCREEN_HEIGHT-40, "    /keelus/pathfinding", false, nil, mononokiFFaceSmall)
+	// BOTTOM BUTTONS (SEARCH OPTIONS)
+	buttonNeighborhood = NewButton(290, 35, 200, SCREEN_HEIGHT-100, neighborhoodTitles[neighborhood], false, nil, mononokiFFace)
+	buttonHeuristic = NewButton(230, 35, 500, SCREEN_HEIGHT-100, heuristicTitles[heuristic], false, nil, mononokiFFace)
+	buttonWeightMinus = NewButton(30, 35, 745, SCREEN_HEIGHT-100, "-", false, nil, mononokiFFace)
+	buttonWeightPlus = NewButton(30, 35, 915, SCREEN_HEIGHT-100, "+", false, nil, mononokiFFace)
+	buttonAgent = NewButton(90, 35, 200, SCREEN_HEIGHT-55, "Agent", false, nil, mononokiFFace)
+	buttonSensorMinus = NewButton(30, 35, 300, SCREEN_HEIGHT-55, "-", false, nil, mononokiFFace)
+	buttonSensorPlus = NewButton(30, 35, 440, SCREEN_HEIGHT-55, "+", false, nil, mononokiFFace)
+	buttonWaypoint = NewButton(120, 35, 500, SCREEN_HEIGHT-55, "Waypoints", false, nil, mononokiFFace)
+	buttonRouteMode = NewButton(230, 35, 630, SCREEN_HEIGHT-55, routeModeTitles[routeMode], false, nil, mononokiFFace)
+	buttonUnit = NewButton(80, 35, 870, SCREEN_HEIGHT-55, "Units", false, nil, mononokiFFace)
+	buttonClusters = NewButton(100, 35, 960, SCREEN_HEIGHT-55, "Clusters", false, nil, mononokiFFace)
+	buttonMapFile = NewButton(190, 35, 960, SCREEN_HEIGHT-100, "", false, nil, mononokiFFace)
+	buttonSave = NewButton(70, 35, 1160, SCREEN_HEIGHT-100, "Save", false, nil, mononokiFFace)
+	buttonLoad = NewButton(70, 35, 1240, SCREEN_HEIGHT-100, "Load", false, nil, mononokiFFace)
+	buttonGreyTerrain = NewButton(150, 35, 1070, SCREEN_HEIGHT-55, "Grey terrain", false, nil, mononokiFFace)
+	buttonRecord = NewButton(80, 35, 1230, SCREEN_HEIGHT-55, "Record", false, nil, mononokiFFace)
+
 	iconGithub = getImage("assets/icons/github.png")
 
 	// LEFT TEXTS
 	categoryTools = "Tools"
 	categoryClear = "Clear"
 	categoryCooldown = "Cooldown"
+	categoryCanvases = "Canvases"
 
 	if err := ebiten.RunGame(&Game{}); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,66 @@
+package pair
+
+import "math"
+
+// Hex grids are stored in offset coordinates: I is the row, J the column, and odd rows are shifted half a cell
+// to the right ("odd-r"). Axial coordinates are stored in a Pair too, with I the row r and J the column q
+// along the rows' slant, which makes the six neighbours of every cell the same six directions.
+
+// Axial returns the axial coordinates of the hex cell at offset coordinates p.
+func (p Pair) Axial() Pair {
+	return Pair{p.I, p.J - (p.I-p.I&1)/2}
+}
+
+// Offset returns the offset coordinates of the hex cell at axial coordinates p.
+func (p Pair) Offset() Pair {
+	return Pair{p.I, p.J + (p.I-p.I&1)/2}
+}
+
+// HexDirections returns the axial directions to the six neighbours of a hex cell.
+func HexDirections() []Pair {
+	return []Pair{{0, 1}, {0, -1}, {1, 0}, {-1, 0}, {-1, 1}, {1, -1}}
+}
+
+// HexNeighbors returns the offset coordinates of the six neighbours of the hex cell at offset coordinates p.
+func (p Pair) HexNeighbors() []Pair {
+	axial := p.Axial()
+	neighbors := make([]Pair, 0, 6)
+	for _, dir := range HexDirections() {
+		neighbors = append(neighbors, axial.Add(dir).Offset())
+	}
+	return neighbors
+}
+
+// HexDist returns the number of moves between the hex cells at offset coordinates p and q.
+func (p Pair) HexDist(q Pair) int {
+	d := p.Axial().Sub(q.Axial())
+	return (abs(d.I) + abs(d.J) + abs(d.I+d.J)) / 2
+}
+
+// HexCenter returns the position of the center of the hex cell at offset coordinates p,
+// in units of the distance between the centers of two neighbours.
+func (p Pair) HexCenter() (x, y float64) {
+	return float64(p.J) + 0.5*float64(p.I&1), math.Sqrt(3) / 2 * float64(p.I)
+}
+
+// HexRound returns the axial coordinates of the hex cell containing the fractional axial position (r, q).
+func HexRound(r, q float64) Pair {
+	s := -r - q
+	ri, qi, si := math.Round(r), math.Round(q), math.Round(s)
+
+	dr, dq, ds := math.Abs(ri-r), math.Abs(qi-q), math.Abs(si-s)
+	if dr > dq && dr > ds {
+		ri = -qi - si
+	} else if dq > ds {
+		qi = -ri - si
+	}
+
+	return Pair{int(ri), int(qi)}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -88,7 +88,7 @@ func (p Pair) Neg() Pair {
 // InBounds reports whether p is inside the bounds.
 // minI and minJ inclusive. maxI and maxJ exclusive.
 func (p Pair) InBounds(minI, minJ, maxI, maxJ int) bool {
-	return p.I >= minI && p.I < maxI && p.J >= minI && p.J < maxJ
+	return p.I >= minI && p.I < maxI && p.J >= minJ && p.J < maxJ
 }
 
 // The following functions use the direction logic based on the directions:
@@ -114,6 +114,26 @@ func Right() Pair {
 	return Pair{0, 1}
 }
 
+// UpLeft returns a Pair in the direction UP-LEFT (-1, -1)
+func UpLeft() Pair {
+	return Pair{-1, -1}
+}
+
+// UpRight returns a Pair in the direction UP-RIGHT (-1, 1)
+func UpRight() Pair {
+	return Pair{-1, 1}
+}
+
+// DownLeft returns a Pair in the direction DOWN-LEFT (1, -1)
+func DownLeft() Pair {
+	return Pair{1, -1}
+}
+
+// DownRight returns a Pair in the direction DOWN-RIGHT (1, 1)
+func DownRight() Pair {
+	return Pair{1, 1}
+}
+
 // TurnL returns the p vector rotated to the left.
 func (p Pair) TurnL() Pair {
 	return Pair{-p.J, p.I}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"os"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Most frames a recording keeps. Longer solves keep every other frame each time it is reached.
+const MAX_RECORD_FRAMES = 100
+
+// Time each recorded frame is shown, and the last one, in hundredths of a second.
+const (
+	RECORD_FRAME_DELAY = 5
+	RECORD_LAST_DELAY  = 300
+)
+
+// Layout of recorded frames: the margin around and between canvases, and the room for the title and stats.
+const (
+	RECORD_MARGIN       = 20
+	RECORD_TITLE_HEIGHT = 30
+	RECORD_STATS_HEIGHT = 80
+)
+
+// File recordings made from the window are saved to.
+const RECORD_FILE = "recording.gif"
+
+// A Recorder captures the canvases side by side after each step of a solve, whatever the cooldown,
+// and saves them as an animated GIF.
+type Recorder struct {
+	frames []*image.Paletted
+	stride int // Steps between captured frames
+	steps  int
+
+	palette color.Palette        // Shared by every frame: the cell colors, then the colors met in the order they are
+	indices map[color.RGBA]uint8 // Index in palette of every color met
+}
+
+func NewRecorder() *Recorder {
+	r := &Recorder{stride: 1, indices: map[color.RGBA]uint8{}}
+
+	// Anti-aliased text blends into more colors than the palette has room for, so cells get theirs first
+	r.index(color.RGBA{0, 0, 0, 255})
+	for _, c := range cellColors() {
+		r.index(c)
+	}
+	return r
+}
+
+// Step captures the canvases after a step of their searches, if it is one of the steps kept.
+func (r *Recorder) Step(canvases []*Canvas) {
+	r.steps++
+	if r.steps%r.stride == 0 {
+		r.Capture(canvases)
+	}
+}
+
+// Capture adds a frame with the canvases as they are, dropping every other frame if there are too many.
+func (r *Recorder) Capture(canvases []*Canvas) {
+	img := frameImage(canvases)
+	frame := image.NewPaletted(img.Rect, nil)
+	for k := range frame.Pix {
+		frame.Pix[k] = r.index(color.RGBA{img.Pix[4*k], img.Pix[4*k+1], img.Pix[4*k+2], img.Pix[4*k+3]})
+	}
+	r.frames = append(r.frames, frame)
+
+	if len(r.frames) > MAX_RECORD_FRAMES {
+		kept := r.frames[:0]
+		for k := 0; k < len(r.frames); k += 2 {
+			kept = append(kept, r.frames[k])
+		}
+		r.frames = kept
+		r.stride *= 2
+	}
+}
+
+// index returns the index in the palette of c, adding it while there is room, or else of the nearest color.
+func (r *Recorder) index(c color.RGBA) uint8 {
+	if k, ok := r.indices[c]; ok {
+		return k
+	}
+
+	k := len(r.palette)
+	if k < 256 {
+		r.palette = append(r.palette, c)
+	} else {
+		k = r.palette.Index(c)
+	}
+	r.indices[c] = uint8(k)
+	return uint8(k)
+}
+
+// Frames returns the number of frames captured.
+func (r *Recorder) Frames() int {
+	return len(r.frames)
+}
+
+// Save writes the frames captured to path as a looping animated GIF, holding the last one longer.
+func (r *Recorder) Save(path string) error {
+	delays := make([]int, len(r.frames))
+	for k, frame := range r.frames {
+		frame.Palette = r.palette
+		delays[k] = RECORD_FRAME_DELAY
+	}
+	if len(delays) > 0 {
+		delays[len(delays)-1] = RECORD_LAST_DELAY
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(file, &gif.GIF{Image: r.frames, Delay: delays}); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// frameImage returns the canvases side by side, each with its title above and stats below, as in the window.
+func frameImage(canvases []*Canvas) *image.RGBA {
+	w, h := RECORD_MARGIN, 0
+	for _, canvas := range canvases {
+		w += canvas.w + RECORD_MARGIN
+		h = max(h, canvas.h)
+	}
+
+	frame := image.NewRGBA(image.Rect(0, 0, w, RECORD_TITLE_HEIGHT+h+RECORD_STATS_HEIGHT))
+	draw.Draw(frame, frame.Rect, image.Black, image.Point{}, draw.Src)
+
+	x := RECORD_MARGIN
+	for _, canvas := range canvases {
+		cells := image.Rect(x, RECORD_TITLE_HEIGHT, x+canvas.w, RECORD_TITLE_HEIGHT+canvas.h)
+		draw.Draw(frame, cells, canvas.Image(), image.Point{}, draw.Src)
+
+		drawFrameText(frame, canvas.Title(), x+canvas.w/2, RECORD_TITLE_HEIGHT-8, canvas.w, color.White)
+		stats, statsColor, extra := canvas.Stats()
+		y := drawFrameText(frame, stats, x+canvas.w/2, cells.Max.Y+22, canvas.w, statsColor)
+		if extra != "" {
+			drawFrameText(frame, extra, x+canvas.w/2, y, canvas.w, color.White)
+		}
+
+		x += canvas.w + RECORD_MARGIN
+	}
+
+	return frame
+}
+
+// drawFrameText draws str centered on x at height y, fit under a canvas width pixels wide,
+// and returns the height of the next line.
+func drawFrameText(frame *image.RGBA, str string, x, y, width int, clr color.Color) int {
+	face, lines, lineHeight := fitLine(str, width)
+	for _, line := range lines {
+		drawer := font.Drawer{Dst: frame, Src: image.NewUniform(clr), Face: face}
+		drawer.Dot = fixed.P(x-drawer.MeasureString(line).Ceil()/2, y)
+		drawer.DrawString(line)
+		y += lineHeight
+	}
+	return y
+}
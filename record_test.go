@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"pathfinding/pair"
+	"pathfinding/solver"
+	"testing"
+)
+
+// Text is anti-aliased into more colors than a GIF palette holds, and must not leave the cells without theirs.
+func TestRecordingKeepsCellColors(t *testing.T) {
+	mononokiFFace = getFont("assets/fonts/mononoki.ttf", 18)
+	mononokiFFaceSmall = getFont("assets/fonts/mononoki.ttf", 14)
+
+	// A canvas for every color of the stats line, the one being solved last
+	var canvases []*Canvas
+	for _, status := range []solver.Status{solver.STATUS_END_NOPATH, solver.STATUS_END_SUCCESS, solver.STATUS_PATHING} {
+		c := &Canvas{w: 220, h: 220, solverName: "Bidirectional A*"}
+		c.SetGrid(solver.NewGrid(20, 20, pair.New(19, 0), pair.New(0, 19)))
+		c.grid.Status = status
+		canvases = append(canvases, c)
+	}
+	solving := canvases[len(canvases)-1]
+	search := solver.NewSearch(context.Background(), &solving.grid, solver.New("Dijkstra"), solver.Options{})
+
+	r := NewRecorder()
+	for k := 0; k < 20; k++ {
+		search.Step()
+		r.Capture(canvases)
+	}
+
+	path := filepath.Join(t.TempDir(), RECORD_FILE)
+	if err := r.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	recording, err := gif.DecodeAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := recording.Image[len(recording.Image)-1]
+	x := RECORD_MARGIN + (len(canvases)-1)*(solving.w+RECORD_MARGIN)
+	visited := 0
+	for i, row := range solving.grid.Cells {
+		for j, node := range row {
+			if !node.Visited || solving.grid.IsFlag(node.Coord) {
+				continue
+			}
+			visited++
+
+			// Top left pixel of the cell
+			got := color.RGBAModel.Convert(last.At(x+j*(cellSize+1), RECORD_TITLE_HEIGHT+i*(cellSize+1)))
+			if got != visitedColor {
+				t.Fatalf("visited cell %v is recorded as %v, not %v", node.Coord, got, visitedColor)
+			}
+		}
+	}
+	if visited == 0 {
+		t.Fatal("no cell was visited")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"pathfinding/solver"
+	"time"
+)
+
+// runScenarios searches every scenario of the Moving AI scenario file at path with the named solver, printing
+// the ones whose path cost is not the optimal one, and reports whether all of them were.
+// The maps are read from mapPath if set, or else from the path each scenario names, relative to the file.
+func runScenarios(path, mapPath, solverName string) bool {
+	if solver.New(solverName) == nil {
+		fmt.Fprintf(os.Stderr, "unknown solver %q\n", solverName)
+		return false
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+	scenarios, err := solver.ReadScenarios(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return false
+	}
+
+	grids := map[string]*solver.Grid{} // By map path, as scenario files usually share a single map
+	optimal, iterations := 0, 0
+	var elapsed time.Duration
+	for k, sc := range scenarios {
+		name := mapPath
+		if name == "" {
+			name = scenarioMapPath(path, sc.Map)
+		}
+
+		grid, ok := grids[name]
+		if !ok {
+			grid, err = readMovingAIMap(name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+				return false
+			}
+			grids[name] = grid
+		}
+
+		result, err := solver.RunScenario(context.Background(), grid, sc, solver.New(solverName), solver.Options{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "scenario %d: %v\n", k+1, err)
+			return false
+		}
+
+		iterations += result.Result.Iterations
+		elapsed += result.Result.Elapsed
+		if result.Optimal {
+			optimal++
+		} else {
+			fmt.Printf("scenario %d (bucket %d) %v -> %v: %s, cost %.8f, optimal %.8f\n", k+1, sc.Bucket,
+				sc.Start, sc.End, result.Result.Outcome, result.Result.PathCost, sc.Optimal)
+		}
+	}
+
+	fmt.Printf("%s: %d/%d scenarios optimal | Iterations: %d | Time: %.2fs\n",
+		solverName, optimal, len(scenarios), iterations, elapsed.Seconds())
+	return optimal == len(scenarios)
+}
+
+// scenarioMapPath returns the path of the map a scenario of the file at path names. Scenario files name it
+// relative to the benchmark set, which is often laid out differently locally, so a map next to the file wins.
+func scenarioMapPath(path, name string) string {
+	nextTo := filepath.Join(filepath.Dir(path), filepath.Base(name))
+	if _, err := os.Stat(nextTo); err == nil {
+		return nextTo
+	}
+	return filepath.Join(filepath.Dir(path), name)
+}
+
+// readMovingAIMap reads the Moving AI map file at path.
+func readMovingAIMap(path string) (*solver.Grid, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	grid, err := solver.ReadMovingAIMap(file)
+	return &grid, err
+}
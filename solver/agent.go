@@ -0,0 +1,178 @@
+package solver
+
+import (
+	"context"
+	"pathfinding/pair"
+	"time"
+)
+
+// An Agent walks from Start to End of a World grid one cell per Tick. It plans on its own Map, which only
+// has the walls it has seen within its sensor radius, and replans whenever a newly seen wall blocks its way.
+// The World status and stats follow the walk: PathLength counts the cells walked, and Iterations the
+// iterations of every plan made.
+type Agent struct {
+	World  *Grid
+	Map    Grid
+	Pos    *Node // Current cell, in World
+	Radius int   // Sensor radius, in cells
+
+	Trail   []*Node // Cells walked so far, in World, Start first
+	Replans int
+
+	newSolver func() Solver
+	route     []pair.Pair // Remaining cells of the current plan, next first
+}
+
+// NewAgent places an agent on the Start of world, with no walls known other than the ones within radius,
+// and makes its first plan with a solver from newSolver.
+func NewAgent(world *Grid, radius int, newSolver func() Solver) *Agent {
+	a := &Agent{World: world, Pos: world.Start, Radius: radius, newSolver: newSolver}
+	a.Trail = []*Node{world.Start}
+
+	a.Map = world.Clone()
+	for i := range a.Map.Cells {
+		for j := range a.Map.Cells[i] {
+			a.Map.Cells[i][j].IsWall = false
+		}
+	}
+
+	world.StartTime = time.Now()
+	world.Status = STATUS_PATHING
+
+	a.sense()
+	a.plan()
+
+	return a
+}
+
+// Tick senses the surroundings, replans if a wall now blocks the route, and moves one cell along it.
+// It reports whether the agent is done, either because it arrived or because there is no way left.
+func (a *Agent) Tick() bool {
+	if a.Done() {
+		return true
+	}
+
+	if a.sense() && a.blocked() {
+		a.Replans++
+		a.plan()
+	}
+
+	if len(a.route) == 0 {
+		a.finish(STATUS_END_NOPATH)
+		return true
+	}
+
+	next := &a.World.Cells[a.route[0].I][a.route[0].J]
+	a.route = a.route[1:]
+
+	a.World.PathCost += a.World.g(*a.Pos, *next)
+	a.World.PathLength++
+	a.Pos = next
+	a.Trail = append(a.Trail, next)
+
+	if a.Pos == a.World.End {
+		a.finish(STATUS_END_SUCCESS)
+		return true
+	}
+
+	return false
+}
+
+// Stop abandons the walk, leaving the world idle.
+func (a *Agent) Stop() {
+	if !a.Done() {
+		a.finish(STATUS_IDLE)
+	}
+}
+
+// Done reports whether the agent is no longer walking.
+func (a *Agent) Done() bool {
+	return a.World.Status != STATUS_PATHING
+}
+
+func (a *Agent) finish(status Status) {
+	a.World.EndTime = time.Now()
+	a.World.Status = status
+}
+
+// sense copies to the map the walls of the world within the sensor radius, reporting whether any was new.
+// The radius is measured in moves, so every cell next to the agent is seen, diagonals and hex cells included.
+func (a *Agent) sense() bool {
+	discovered := false
+	for i := a.Pos.Coord.I - a.Radius; i <= a.Pos.Coord.I+a.Radius; i++ {
+		for j := a.Pos.Coord.J - a.Radius; j <= a.Pos.Coord.J+a.Radius; j++ {
+			p := pair.New(i, j)
+			if !p.InBounds(0, 0, len(a.World.Cells), len(a.World.Cells[0])) || a.dist(p) > float64(a.Radius) {
+				continue
+			}
+
+			if a.World.Cells[i][j].IsWall && !a.Map.Cells[i][j].IsWall {
+				a.Map.Cells[i][j].IsWall = true
+				discovered = true
+			}
+		}
+	}
+
+	return discovered
+}
+
+// dist returns the distance from the agent to p: in moves on 8-way and hex grids, and straight on 4-way grids,
+// where that still reaches every cell next to the agent.
+func (a *Agent) dist(p pair.Pair) float64 {
+	d := p.Sub(a.Pos.Coord)
+	switch a.World.Neighborhood {
+	case NEIGHBORHOOD_4:
+		return a.Pos.Coord.Dist(p)
+	case NEIGHBORHOOD_HEX:
+		return float64(a.Pos.Coord.HexDist(p))
+	}
+	return float64(max(d.I, -d.I, d.J, -d.J))
+}
+
+// blocked reports whether a known wall lies on the remaining route, or, when corners can not be cut, beside
+// one of its diagonal moves.
+func (a *Agent) blocked() bool {
+	prev := a.Pos.Coord
+	for _, p := range a.route {
+		if a.Map.Cells[p.I][p.J].IsWall {
+			return true
+		}
+		if a.Map.Neighborhood == NEIGHBORHOOD_8_NO_CORNERS && p.I != prev.I && p.J != prev.J &&
+			(a.Map.Cells[prev.I][p.J].IsWall || a.Map.Cells[p.I][prev.J].IsWall) {
+			return true
+		}
+		prev = p
+	}
+	return false
+}
+
+// plan searches the map from the current cell, and keeps the cells of the path found as the route.
+func (a *Agent) plan() {
+	a.Map.Start = &a.Map.Cells[a.Pos.Coord.I][a.Pos.Coord.J]
+	a.Map.Restart(true)
+
+	result := Solve(context.Background(), &a.Map, a.newSolver(), Options{})
+	a.World.Iterations += result.Iterations
+
+	a.route = nil
+	if result.Outcome != OUTCOME_SUCCESS {
+		return
+	}
+
+	for node := a.Map.End; node.Prev != nil; node = node.Prev {
+		// Any-angle links are walked through the cells their line crosses
+		var line []pair.Pair
+		a.Map.walkLine(node.Prev.Coord, node.Coord, false, func(p pair.Pair) bool {
+			line = append(line, p)
+			return true
+		})
+
+		for k := len(line) - 1; k > 0; k-- {
+			a.route = append(a.route, line[k])
+		}
+	}
+
+	for l, r := 0, len(a.route)-1; l < r; l, r = l+1, r-1 {
+		a.route[l], a.route[r] = a.route[r], a.route[l]
+	}
+}
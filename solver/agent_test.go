@@ -0,0 +1,41 @@
+package solver
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Agents only see walls within their sensor radius, so with the smallest one every step goes next to walls
+// they have just found. They must never walk into one, nor cut the corner of one where that is not allowed.
+func TestAgentAvoidsHiddenWalls(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+
+	for _, neighborhood := range testNeighborhoods {
+		for k := 0; k < 100; k++ {
+			world := randomGrid(rng, 5+rng.Intn(20), 5+rng.Intn(20), neighborhood, 10+rng.Intn(35), rng.Intn(2) == 0)
+			optimal, _ := solve(t, &world, "Dijkstra")
+
+			agent := NewAgent(&world, 1, func() Solver { return New("A*") })
+			for prev := agent.Pos; !agent.Tick(); prev = agent.Pos {
+				if agent.Pos.IsWall {
+					t.Fatalf("agent on %s grid %d: walked into the wall at %v", neighborhood, k, agent.Pos.Coord)
+				}
+				if !neighbors(&world, prev, agent.Pos) {
+					t.Fatalf("agent on %s grid %d: moved from %v to %v", neighborhood, k, prev.Coord, agent.Pos.Coord)
+				}
+				if len(agent.Trail) > 4*len(world.Cells)*len(world.Cells[0]) {
+					t.Fatalf("agent on %s grid %d: still walking after %d cells", neighborhood, k, len(agent.Trail))
+				}
+			}
+
+			for l := 1; l < len(agent.Trail); l++ {
+				if agent.Trail[l].IsWall || !neighbors(&world, agent.Trail[l-1], agent.Trail[l]) {
+					t.Fatalf("agent on %s grid %d: moved from %v to %v", neighborhood, k, agent.Trail[l-1].Coord, agent.Trail[l].Coord)
+				}
+			}
+			if (world.Status == STATUS_END_SUCCESS) != (optimal.Status == STATUS_END_SUCCESS) {
+				t.Fatalf("agent on %s grid %d: status %s, Dijkstra got %s", neighborhood, k, world.Status, optimal.Status)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package solver
+
+import (
+	"container/heap"
+	"math"
+)
+
+// astar is a best-first search ordered by f = gWeight*g + hWeight*h, which covers A*, weighted A* and greedy best-first.
+type astar struct {
+	name     string
+	gWeight  float64
+	weighted bool // Whether h is scaled by the grid HeuristicWeight
+
+	grid    *Grid
+	pq      PriorityQueue
+	hWeight float64
+}
+
+func init() {
+	Register("A*", func() Solver { return &astar{name: "A*", gWeight: 1} })
+	Register("Weighted A*", func() Solver { return &astar{name: "Weighted A*", gWeight: 1, weighted: true} })
+	Register("Greedy best-first", func() Solver { return &astar{name: "Greedy best-first", gWeight: 0} })
+}
+
+func (a *astar) Name() string {
+	return a.name
+}
+
+func (a *astar) Init(grid *Grid) {
+	a.grid = grid
+	a.hWeight = 1
+	if a.weighted {
+		a.hWeight = grid.HeuristicWeight
+	}
+
+	for i := range grid.Cells {
+		for j := range grid.Cells[i] {
+			grid.Cells[i][j].Gcost = math.MaxFloat64
+			grid.Cells[i][j].Cost = math.MaxFloat64
+		}
+	}
+
+	grid.Start.Gcost = 0
+	grid.Start.Cost = a.f(*grid.Start)
+
+	a.pq = PriorityQueue{grid.Start}
+	heap.Init(&a.pq)
+}
+
+func (a *astar) Step() StepResult {
+	if a.pq.Len() == 0 {
+		return StepResult{Done: true}
+	}
+
+	current := heap.Pop(&a.pq).(*Node)
+	current.Visited = true
+
+	if current == a.grid.End {
+		return StepResult{Popped: current, Done: true}
+	}
+
+	var pushed []*Node
+	for _, neighbor := range a.grid.Neighbors(current) {
+		if neighbor.Visited {
+			continue
+		}
+
+		gcost := current.Gcost + a.grid.g(*current, *neighbor)
+		if gcost < neighbor.Gcost {
+			neighbor.Prev = current
+			neighbor.Gcost = gcost
+			neighbor.Cost = a.f(*neighbor)
+
+			if neighbor.Added {
+				heap.Fix(&a.pq, neighbor.index)
+			} else {
+				neighbor.Added = true
+				heap.Push(&a.pq, neighbor)
+			}
+			pushed = append(pushed, neighbor)
+		}
+	}
+
+	return StepResult{Popped: current, Pushed: pushed, Done: a.pq.Len() == 0}
+}
+
+func (a *astar) Result() Status {
+	if !a.grid.constructPath() {
+		return STATUS_END_NOPATH
+	}
+	return STATUS_END_SUCCESS
+}
+
+func (a *astar) f(n Node) float64 {
+	return a.gWeight*n.Gcost + a.hWeight*a.grid.h(n)
+}
@@ -0,0 +1,56 @@
+package solver
+
+type bfs struct {
+	grid  *Grid
+	queue []*Node
+}
+
+func init() {
+	Register("Breadth-first search", func() Solver { return &bfs{} })
+}
+
+func (b *bfs) Name() string {
+	return "Breadth-first search"
+}
+
+func (b *bfs) Init(grid *Grid) {
+	b.grid = grid
+
+	grid.Start.Added = true
+	b.queue = []*Node{grid.Start}
+}
+
+func (b *bfs) Step() StepResult {
+	if len(b.queue) == 0 {
+		return StepResult{Done: true}
+	}
+
+	u := b.queue[0]
+	b.queue = b.queue[1:]
+	u.Visited = true
+
+	if u == b.grid.End {
+		return StepResult{Popped: u, Done: true}
+	}
+
+	var pushed []*Node
+	for _, neighbor := range b.grid.Neighbors(u) {
+		if neighbor.Added {
+			continue
+		}
+
+		neighbor.Added = true
+		neighbor.Prev = u
+		b.queue = append(b.queue, neighbor)
+		pushed = append(pushed, neighbor)
+	}
+
+	return StepResult{Popped: u, Pushed: pushed, Done: len(b.queue) == 0}
+}
+
+func (b *bfs) Result() Status {
+	if !b.grid.constructPath() {
+		return STATUS_END_NOPATH
+	}
+	return STATUS_END_SUCCESS
+}
@@ -0,0 +1,220 @@
+package solver
+
+import (
+	"container/heap"
+	"math"
+)
+
+// frontierEntry is a node queued in a frontier with the priority it had when pushed.
+// Entries whose node has since been closed or improved are stale and skipped when popped.
+type frontierEntry struct {
+	node *Node
+	f    float64
+	f2   float64 // Secondary priority, for equal f
+	seq  int
+}
+
+// frontier is a priority queue of entries, first in first out among equal priorities.
+type frontier []frontierEntry
+
+func (fr frontier) Len() int { return len(fr) }
+func (fr frontier) Less(i, j int) bool {
+	if fr[i].f != fr[j].f {
+		return fr[i].f < fr[j].f
+	}
+	if fr[i].f2 != fr[j].f2 {
+		return fr[i].f2 < fr[j].f2
+	}
+	return fr[i].seq < fr[j].seq
+}
+func (fr frontier) Swap(i, j int) { fr[i], fr[j] = fr[j], fr[i] }
+
+func (fr *frontier) Push(x interface{}) { *fr = append(*fr, x.(frontierEntry)) }
+func (fr *frontier) Pop() interface{} {
+	old := *fr
+	entry := old[len(old)-1]
+	*fr = old[:len(old)-1]
+	return entry
+}
+
+// side is the state of one of the two searches of a bidirectional solver.
+type side struct {
+	fromEnd bool
+	target  *Node
+
+	gcost  [][]float64
+	prev   [][]*Node
+	closed [][]bool
+	open   frontier
+}
+
+// bidirectional grows a search from Start and another from End, one step each in turn, until they meet.
+type bidirectional struct {
+	name  string
+	astar bool // Order the frontiers by g+h and use the cell weights, instead of expanding them breadth first
+
+	grid  *Grid
+	sides [2]*side
+	turn  int
+	seq   int
+
+	best float64 // Cost of the best path found through a meeting node
+	meet *Node
+}
+
+func init() {
+	Register("Bidirectional BFS", func() Solver { return &bidirectional{name: "Bidirectional BFS"} })
+	Register("Bidirectional A*", func() Solver { return &bidirectional{name: "Bidirectional A*", astar: true} })
+}
+
+func (b *bidirectional) Name() string {
+	return b.name
+}
+
+func (b *bidirectional) Init(grid *Grid) {
+	b.grid = grid
+	b.best = math.MaxFloat64
+	b.meet = nil
+	b.turn = 0
+
+	b.sides[0] = b.newSide(grid.Start, grid.End, false)
+	b.sides[1] = b.newSide(grid.End, grid.Start, true)
+}
+
+func (b *bidirectional) newSide(from, target *Node, fromEnd bool) *side {
+	s := &side{fromEnd: fromEnd, target: target}
+
+	s.gcost = make([][]float64, len(b.grid.Cells))
+	s.prev = make([][]*Node, len(b.grid.Cells))
+	s.closed = make([][]bool, len(b.grid.Cells))
+	for i := range b.grid.Cells {
+		s.gcost[i] = make([]float64, len(b.grid.Cells[i]))
+		s.prev[i] = make([]*Node, len(b.grid.Cells[i]))
+		s.closed[i] = make([]bool, len(b.grid.Cells[i]))
+		for j := range s.gcost[i] {
+			s.gcost[i][j] = math.MaxFloat64
+		}
+	}
+
+	s.gcost[from.Coord.I][from.Coord.J] = 0
+	from.Added = true
+	from.FromEnd = fromEnd
+	b.push(s, from)
+
+	return s
+}
+
+func (b *bidirectional) push(s *side, n *Node) {
+	f := s.gcost[n.Coord.I][n.Coord.J]
+	if b.astar {
+		f += b.grid.hTo(*n, *s.target)
+	}
+
+	b.seq++
+	heap.Push(&s.open, frontierEntry{node: n, f: f, seq: b.seq})
+}
+
+// cost returns the cost of the move between the adjacent cells from and to, in the real direction of travel.
+func (b *bidirectional) cost(s *side, from, to *Node) float64 {
+	if !b.astar {
+		return 1
+	}
+	if s.fromEnd {
+		return b.grid.g(*to, *from)
+	}
+	return b.grid.g(*from, *to)
+}
+
+func (b *bidirectional) Step() StepResult {
+	s, other := b.sides[b.turn], b.sides[1-b.turn]
+	b.turn = 1 - b.turn
+
+	if b.exhausted() {
+		return StepResult{Done: true}
+	}
+	if s.open.Len() == 0 {
+		s, other = other, s
+	}
+
+	// With a path already found, stop once the frontiers can no longer improve it. Without a heuristic,
+	// a shorter path would have to go through the cheapest cells of both frontiers.
+	if b.meet != nil {
+		bound := s.open[0].f
+		if !b.astar && other.open.Len() > 0 {
+			bound += other.open[0].f
+		}
+		if bound >= b.best {
+			return StepResult{Done: true}
+		}
+	}
+
+	entry := heap.Pop(&s.open).(frontierEntry)
+	u := entry.node
+	if s.closed[u.Coord.I][u.Coord.J] {
+		return StepResult{Popped: u, Done: b.exhausted()}
+	}
+
+	s.closed[u.Coord.I][u.Coord.J] = true
+	if !u.Visited {
+		u.Visited = true
+		u.FromEnd = s.fromEnd
+	}
+
+	var pushed []*Node
+	for _, neighbor := range b.grid.Neighbors(u) {
+		ni, nj := neighbor.Coord.I, neighbor.Coord.J
+		if s.closed[ni][nj] {
+			continue
+		}
+
+		gcost := s.gcost[u.Coord.I][u.Coord.J] + b.cost(s, u, neighbor)
+		if gcost >= s.gcost[ni][nj] {
+			continue
+		}
+
+		s.gcost[ni][nj] = gcost
+		s.prev[ni][nj] = u
+		if !neighbor.Added {
+			neighbor.Added = true
+			neighbor.FromEnd = s.fromEnd
+		}
+		b.push(s, neighbor)
+		pushed = append(pushed, neighbor)
+
+		if other.gcost[ni][nj] != math.MaxFloat64 && gcost+other.gcost[ni][nj] < b.best {
+			b.best = gcost + other.gcost[ni][nj]
+			b.meet = neighbor
+		}
+	}
+
+	return StepResult{Popped: u, Pushed: pushed, Done: b.exhausted()}
+}
+
+// exhausted reports whether there is nothing left to expand: both frontiers are empty, or one is before they met,
+// so there is no path. Once they met, the other one goes on until it can no longer improve the path.
+func (b *bidirectional) exhausted() bool {
+	empty0, empty1 := b.sides[0].open.Len() == 0, b.sides[1].open.Len() == 0
+	return empty0 && empty1 || (empty0 || empty1) && b.meet == nil
+}
+
+// Result stitches the chain from Start to the meeting node with the one from the meeting node to End into Prev.
+func (b *bidirectional) Result() Status {
+	if b.meet != nil {
+		forward, backward := b.sides[0], b.sides[1]
+
+		for node := b.meet; node != b.grid.Start; node = node.Prev {
+			node.Prev = forward.prev[node.Coord.I][node.Coord.J]
+		}
+
+		for node := b.meet; node != b.grid.End; {
+			next := backward.prev[node.Coord.I][node.Coord.J]
+			next.Prev = node
+			node = next
+		}
+	}
+
+	if !b.grid.constructPath() {
+		return STATUS_END_NOPATH
+	}
+	return STATUS_END_SUCCESS
+}
@@ -0,0 +1,62 @@
+package solver
+
+type dfs struct {
+	grid  *Grid
+	stack []*Node
+}
+
+func init() {
+	Register("Depth-first search", func() Solver { return &dfs{} })
+}
+
+func (d *dfs) Name() string {
+	return "Depth-first search"
+}
+
+func (d *dfs) Init(grid *Grid) {
+	d.grid = grid
+
+	grid.Start.Added = true
+	d.stack = []*Node{grid.Start}
+}
+
+func (d *dfs) Step() StepResult {
+	if len(d.stack) == 0 {
+		return StepResult{Done: true}
+	}
+
+	u := d.stack[len(d.stack)-1]
+	d.stack = d.stack[:len(d.stack)-1]
+
+	if u.Visited {
+		return StepResult{Popped: u, Done: len(d.stack) == 0}
+	}
+
+	u.Visited = true
+
+	if u == d.grid.End {
+		return StepResult{Popped: u, Done: true}
+	}
+
+	var pushed []*Node
+	for _, neighbor := range d.grid.Neighbors(u) {
+		if neighbor.Visited {
+			continue
+		}
+
+		// A node may be pushed several times, the last push is the one popped first, so it owns Prev
+		neighbor.Added = true
+		neighbor.Prev = u
+		d.stack = append(d.stack, neighbor)
+		pushed = append(pushed, neighbor)
+	}
+
+	return StepResult{Popped: u, Pushed: pushed, Done: len(d.stack) == 0}
+}
+
+func (d *dfs) Result() Status {
+	if !d.grid.constructPath() {
+		return STATUS_END_NOPATH
+	}
+	return STATUS_END_SUCCESS
+}
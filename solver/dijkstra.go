@@ -0,0 +1,81 @@
+package solver
+
+import (
+	"container/heap"
+	"math"
+)
+
+type dijkstra struct {
+	grid *Grid
+	pq   PriorityQueue
+}
+
+func init() {
+	Register("Dijkstra", func() Solver { return &dijkstra{} })
+}
+
+func (d *dijkstra) Name() string {
+	return "Dijkstra"
+}
+
+func (d *dijkstra) Init(grid *Grid) {
+	d.grid = grid
+
+	for i, row := range grid.Cells {
+		for j := range row {
+			grid.Cells[i][j].Cost = math.MaxInt
+		}
+	}
+
+	grid.Start.Cost = 0
+
+	d.pq = PriorityQueue{grid.Start}
+	heap.Init(&d.pq)
+}
+
+func (d *dijkstra) Step() StepResult {
+	if d.pq.Len() == 0 {
+		return StepResult{Done: true}
+	}
+
+	u := heap.Pop(&d.pq).(*Node)
+
+	if u.Visited {
+		return StepResult{Popped: u, Done: d.pq.Len() == 0}
+	}
+
+	if u == d.grid.End {
+		return StepResult{Popped: u, Done: true}
+	}
+
+	u.Visited = true
+
+	var pushed []*Node
+	for _, neighbor := range d.grid.Neighbors(u) {
+		if neighbor.Visited {
+			continue
+		}
+
+		alt := u.Cost + d.grid.g(*u, *neighbor)
+		if alt < neighbor.Cost {
+			neighbor.Cost = alt
+			neighbor.Prev = u
+			if neighbor.Added {
+				heap.Fix(&d.pq, neighbor.index)
+			} else {
+				neighbor.Added = true
+				heap.Push(&d.pq, neighbor)
+			}
+			pushed = append(pushed, neighbor)
+		}
+	}
+
+	return StepResult{Popped: u, Pushed: pushed, Done: d.pq.Len() == 0}
+}
+
+func (d *dijkstra) Result() Status {
+	if !d.grid.constructPath() {
+		return STATUS_END_NOPATH
+	}
+	return STATUS_END_SUCCESS
+}
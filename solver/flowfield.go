@@ -0,0 +1,83 @@
+package solver
+
+import (
+	"container/heap"
+	"math"
+)
+
+// flowField runs Dijkstra backwards from End over the whole grid, so every cell gets the cost of reaching End
+// in Cost and its downhill neighbour in Next. Any number of units can follow Next to End from wherever they are.
+type flowField struct {
+	grid *Grid
+	pq   PriorityQueue
+}
+
+func init() {
+	Register("Flow field", func() Solver { return &flowField{} })
+}
+
+func (f *flowField) Name() string {
+	return "Flow field"
+}
+
+func (f *flowField) Init(grid *Grid) {
+	f.grid = grid
+
+	for i, row := range grid.Cells {
+		for j := range row {
+			grid.Cells[i][j].Cost = math.MaxInt
+		}
+	}
+
+	grid.End.Cost = 0
+	grid.End.Added = true
+
+	f.pq = PriorityQueue{grid.End}
+	heap.Init(&f.pq)
+}
+
+func (f *flowField) Step() StepResult {
+	if f.pq.Len() == 0 {
+		return StepResult{Done: true}
+	}
+
+	v := heap.Pop(&f.pq).(*Node)
+	v.Visited = true
+
+	var pushed []*Node
+	for _, u := range f.grid.Neighbors(v) {
+		if u.Visited {
+			continue
+		}
+
+		// Stepping from u into v costs the weight of v
+		alt := v.Cost + f.grid.g(*u, *v)
+		if alt < u.Cost {
+			u.Cost = alt
+			u.Next = v
+			if u.Added {
+				heap.Fix(&f.pq, u.index)
+			} else {
+				u.Added = true
+				heap.Push(&f.pq, u)
+			}
+			pushed = append(pushed, u)
+		}
+	}
+
+	return StepResult{Popped: v, Pushed: pushed, Done: f.pq.Len() == 0}
+}
+
+// Result builds the path Start would follow down the field.
+func (f *flowField) Result() Status {
+	if f.grid.Start.Next == nil && f.grid.Start != f.grid.End {
+		return STATUS_END_NOPATH
+	}
+
+	for n := f.grid.Start; n != f.grid.End; n = n.Next {
+		n.Next.Prev = n
+	}
+
+	f.grid.constructPath()
+	return STATUS_END_SUCCESS
+}
@@ -0,0 +1,393 @@
+package solver
+
+import (
+	"context"
+	"math"
+	"pathfinding/pair"
+	"time"
+)
+
+const BASE_WEIGHT = 1
+
+// Terrain weights, the cost of stepping into a cell of each kind.
+const (
+	WEIGHT_ROAD  = BASE_WEIGHT
+	WEIGHT_GRASS = 2
+	WEIGHT_MUD   = 5
+	WEIGHT_WATER = 10
+)
+
+// Neighborhood is the set of moves allowed from a cell.
+type Neighborhood string
+
+const (
+	NEIGHBORHOOD_4            Neighborhood = "NEIGHBORHOOD_4"            // Up, down, left and right
+	NEIGHBORHOOD_8            Neighborhood = "NEIGHBORHOOD_8"            // Also diagonals, even between two walls
+	NEIGHBORHOOD_8_NO_CORNERS Neighborhood = "NEIGHBORHOOD_8_NO_CORNERS" // Also diagonals, only if neither adjacent side is a wall
+	NEIGHBORHOOD_HEX          Neighborhood = "NEIGHBORHOOD_HEX"          // Cells are hexagons with six neighbours, see pair.Pair.Axial
+)
+
+// Heuristic is the distance estimate to the end used by informed searches. Hex grids always use the hex distance.
+type Heuristic string
+
+const (
+	HEURISTIC_MANHATTAN Heuristic = "HEURISTIC_MANHATTAN"
+	HEURISTIC_OCTILE    Heuristic = "HEURISTIC_OCTILE"
+	HEURISTIC_CHEBYSHEV Heuristic = "HEURISTIC_CHEBYSHEV"
+	HEURISTIC_EUCLIDEAN Heuristic = "HEURISTIC_EUCLIDEAN"
+)
+
+type Status string
+
+const (
+	STATUS_IDLE        Status = "STATUS_IDLE"
+	STATUS_PATHING     Status = "STATUS_PATHING"
+	STATUS_END_SUCCESS Status = "STATUS_END_SUCCESS"
+	STATUS_END_NOPATH  Status = "STATUS_END_NOPATH"
+)
+
+type Node struct {
+	Coord  pair.Pair
+	IsWall bool
+	Weight int // Cost of stepping into this cell
+	Prev   *Node
+
+	Cost  float64 // Cost for Dijkstra, Fcost for A*
+	Gcost float64 // For A*
+
+	Visited bool
+	Added   bool
+	FromEnd bool // Reached by the frontier grown from End, in bidirectional searches
+
+	IsJumpPoint bool // Successor found by Jump Point Search
+
+	Next *Node // Downhill neighbour toward End, in flow fields
+
+	IsPath bool
+
+	index int
+}
+
+type Grid struct {
+	Cells [][]Node
+	Start *Node
+	End   *Node
+
+	Waypoints []*Node // Extra stops of a route, in the order they were placed
+	Visits    []*Node // Stops of the last route found, Start first, in the order they are visited
+
+	Tasks  []Task  // Units to move at once, in multi-agent searches
+	Tracks []Track // Tracks of the units found by the last multi-agent search, in the order of Tasks
+
+	Status Status
+
+	Neighborhood    Neighborhood
+	Heuristic       Heuristic
+	HeuristicWeight float64 // Factor applied to the heuristic by weighted searches
+
+	PathLength int
+	PathCost   float64
+	Iterations int
+
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// NewGrid returns an empty grid of rows*cols cells, with flags at start and end.
+func NewGrid(rows, cols int, start, end pair.Pair) Grid {
+	cells := make([][]Node, rows)
+	for i := 0; i < rows; i++ {
+		cells[i] = make([]Node, cols)
+		for j := 0; j < cols; j++ {
+			cells[i][j] = Node{
+				Coord:  pair.New(i, j),
+				Weight: BASE_WEIGHT,
+				Cost:   math.MaxInt}
+		}
+	}
+
+	return Grid{Cells: cells, Start: &cells[start.I][start.J], End: &cells[end.I][end.J], Status: STATUS_IDLE,
+		Neighborhood: NEIGHBORHOOD_4, Heuristic: HEURISTIC_MANHATTAN, HeuristicWeight: 1}
+}
+
+func (g *Grid) Restart(keepLayout bool) {
+	cells := make([][]Node, len(g.Cells))
+	for i := 0; i < len(g.Cells); i++ {
+		cells[i] = make([]Node, len(g.Cells[i]))
+		for j := 0; j < len(g.Cells[i]); j++ {
+			cells[i][j] = Node{Coord: pair.New(i, j), IsWall: keepLayout && g.Cells[i][j].IsWall, Weight: BASE_WEIGHT}
+			if keepLayout {
+				cells[i][j].Weight = g.Cells[i][j].Weight
+			}
+		}
+	}
+
+	g.Start = &cells[g.Start.Coord.I][g.Start.Coord.J]
+	g.End = &cells[g.End.Coord.I][g.End.Coord.J]
+
+	waypoints := make([]*Node, len(g.Waypoints))
+	for k, w := range g.Waypoints {
+		waypoints[k] = &cells[w.Coord.I][w.Coord.J]
+	}
+	g.Waypoints = waypoints
+	g.Visits = nil
+
+	tasks := make([]Task, len(g.Tasks))
+	for k, task := range g.Tasks {
+		tasks[k] = Task{Start: &cells[task.Start.Coord.I][task.Start.Coord.J], End: &cells[task.End.Coord.I][task.End.Coord.J]}
+	}
+	g.Tasks = tasks
+	g.Tracks = nil
+
+	g.PathLength = 0
+	g.PathCost = 0
+	g.Iterations = 0
+	g.Status = STATUS_IDLE
+	g.Cells = cells
+
+	g.StartTime = time.Now()
+	g.EndTime = time.Now()
+}
+
+// Clone returns a copy of the grid layout, flags and settings, with no search state.
+func (g *Grid) Clone() Grid {
+	clone := *g
+	clone.Restart(true)
+	return clone
+}
+
+// OptimalCost returns the cost of the shortest path from Start to End, or false if there is none.
+// The search runs on a clone, leaving grid untouched.
+func (g *Grid) OptimalCost() (float64, bool) {
+	clone := g.Clone()
+	search := NewSearch(context.Background(), &clone, &dijkstra{}, Options{})
+	for !search.Step().Done {
+	}
+
+	return clone.PathCost, clone.Status == STATUS_END_SUCCESS
+}
+
+// Neighbors returns the cells reachable in one move from n, according to the grid Neighborhood.
+func (grid *Grid) Neighbors(n *Node) []*Node {
+	if grid.Neighborhood == NEIGHBORHOOD_HEX {
+		neighbors := make([]*Node, 0, 6)
+		for _, p := range n.Coord.HexNeighbors() {
+			if grid.walkable(p) {
+				neighbors = append(neighbors, &grid.Cells[p.I][p.J])
+			}
+		}
+		return neighbors
+	}
+
+	directions := []pair.Pair{pair.Up(), pair.Down(), pair.Left(), pair.Right()}
+	if grid.Neighborhood != NEIGHBORHOOD_4 {
+		directions = append(directions, pair.UpLeft(), pair.UpRight(), pair.DownLeft(), pair.DownRight())
+	}
+
+	neighbors := make([]*Node, 0, len(directions))
+	for _, dir := range directions {
+		if !grid.walkable(n.Coord.Add(dir)) {
+			continue
+		}
+
+		if grid.Neighborhood == NEIGHBORHOOD_8_NO_CORNERS && dir.I != 0 && dir.J != 0 {
+			if !grid.walkable(n.Coord.Add(pair.New(dir.I, 0))) || !grid.walkable(n.Coord.Add(pair.New(0, dir.J))) {
+				continue
+			}
+		}
+
+		neighbors = append(neighbors, &grid.Cells[n.Coord.I+dir.I][n.Coord.J+dir.J])
+	}
+
+	return neighbors
+}
+
+// IsFlag reports whether the cell at p is Start, End, a waypoint, or the start or end of a task.
+func (grid *Grid) IsFlag(p pair.Pair) bool {
+	k, _ := grid.TaskAt(p)
+	return grid.Start.Coord == p || grid.End.Coord == p || grid.IsWaypoint(p) || k != -1
+}
+
+// walkable reports whether p is inside the grid and not a wall.
+func (grid *Grid) walkable(p pair.Pair) bool {
+	return p.InBounds(0, 0, len(grid.Cells), len(grid.Cells[0])) && !grid.Cells[p.I][p.J].IsWall
+}
+
+// g returns the cost of moving from a to the adjacent cell b, given by the weight of b.
+func (grid *Grid) g(a, b Node) float64 {
+	if grid.Neighborhood != NEIGHBORHOOD_HEX && a.Coord.I != b.Coord.I && a.Coord.J != b.Coord.J {
+		return float64(b.Weight) * math.Sqrt2
+	}
+	return float64(b.Weight)
+}
+
+func (grid Grid) h(a Node) float64 {
+	tb := float64(1)/float64(len(grid.Cells)*len(grid.Cells[0])) + 1 // Tie breaker
+	return grid.hTo(a, *grid.End) * tb
+}
+
+// hTo returns the heuristic distance between a and any target cell, with no tie breaker.
+func (grid Grid) hTo(a, target Node) float64 {
+	if grid.Neighborhood == NEIGHBORHOOD_HEX {
+		return BASE_WEIGHT * float64(a.Coord.HexDist(target.Coord))
+	}
+
+	dy := math.Abs(float64(a.Coord.I - target.Coord.I))
+	dx := math.Abs(float64(a.Coord.J - target.Coord.J))
+
+	var heuristic float64
+	switch grid.Heuristic {
+	case HEURISTIC_OCTILE:
+		heuristic = BASE_WEIGHT * (dx + dy + (math.Sqrt2-2)*math.Min(dx, dy))
+	case HEURISTIC_CHEBYSHEV:
+		heuristic = BASE_WEIGHT * math.Max(dx, dy)
+	case HEURISTIC_EUCLIDEAN:
+		heuristic = BASE_WEIGHT * math.Sqrt(dx*dx+dy*dy)
+	default:
+		heuristic = BASE_WEIGHT * (dx + dy)
+	}
+
+	return heuristic
+}
+
+// constructPath marks the cells of the path following Prev back from End, and sets its length and cost.
+// Links between cells that are not adjacent (any-angle paths) cover the cells crossed by the straight line between them.
+func (grid *Grid) constructPath() bool {
+	node := grid.End
+
+	if node.Prev == nil {
+		grid.PathLength = -1
+		return false
+	} else {
+		for node != nil {
+			if node != grid.Start && node != grid.End {
+				grid.PathLength++
+			}
+
+			if node.Prev != nil {
+				if grid.Adjacent(*node.Prev, *node) {
+					grid.PathCost += grid.g(*node.Prev, *node)
+				} else {
+					grid.PathCost += grid.euclidean(node.Prev.Coord, node.Coord)
+					grid.walkLine(node.Prev.Coord, node.Coord, false, func(p pair.Pair) bool {
+						crossed := &grid.Cells[p.I][p.J]
+						if !crossed.IsPath && crossed != node && crossed != node.Prev {
+							crossed.IsPath = true
+							grid.PathLength++
+						}
+						return true
+					})
+				}
+			}
+
+			node.IsPath = true
+			node = node.Prev
+		}
+	}
+
+	return true
+}
+
+// Adjacent reports whether a and b are different cells one move apart, diagonals included.
+func (grid *Grid) Adjacent(a, b Node) bool {
+	if grid.Neighborhood == NEIGHBORHOOD_HEX {
+		return a.Coord.HexDist(b.Coord) == 1
+	}
+
+	di, dj := a.Coord.I-b.Coord.I, a.Coord.J-b.Coord.J
+	return (di != 0 || dj != 0) && di >= -1 && di <= 1 && dj >= -1 && dj <= 1
+}
+
+// euclidean returns the straight line distance between the centers of a and b on uniform terrain.
+func (grid *Grid) euclidean(a, b pair.Pair) float64 {
+	if grid.Neighborhood == NEIGHBORHOOD_HEX {
+		ax, ay := a.HexCenter()
+		bx, by := b.HexCenter()
+		return BASE_WEIGHT * math.Hypot(ax-bx, ay-by)
+	}
+	return BASE_WEIGHT * a.Dist(b)
+}
+
+// LineOfSight reports whether the straight line between the centers of a and b only crosses walkable cells.
+// A line going exactly through a corner is blocked if either of the two cells sharing it is a wall.
+func (grid *Grid) LineOfSight(a, b pair.Pair) bool {
+	return grid.walkLine(a, b, true, grid.walkable)
+}
+
+// walkLine calls visit on every cell crossed by the line between the centers of a and b, from a to b,
+// stopping as soon as visit returns false. It reports whether the whole line was walked.
+// If corners is set, the two cells touching a corner the line goes exactly through are visited too.
+func (grid *Grid) walkLine(a, b pair.Pair, corners bool, visit func(p pair.Pair) bool) bool {
+	if grid.Neighborhood == NEIGHBORHOOD_HEX {
+		return grid.walkHexLine(a, b, corners, visit)
+	}
+
+	ni, nj := b.I-a.I, b.J-a.J
+	si, sj := sign(ni), sign(nj)
+	ni, nj = ni*si, nj*sj
+
+	p := a
+	if !visit(p) {
+		return false
+	}
+
+	for ii, ij := 0, 0; ii < ni || ij < nj; {
+		// Compare where the line leaves the current cell: through a horizontal side, a vertical one, or a corner
+		decision := (1+2*ij)*ni - (1+2*ii)*nj
+		if decision == 0 {
+			if corners && (!visit(p.Add(pair.New(si, 0))) || !visit(p.Add(pair.New(0, sj)))) {
+				return false
+			}
+			p = p.Add(pair.New(si, sj))
+			ii++
+			ij++
+		} else if decision > 0 {
+			p = p.Add(pair.New(si, 0))
+			ii++
+		} else {
+			p = p.Add(pair.New(0, sj))
+			ij++
+		}
+
+		if !visit(p) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// walkHexLine is walkLine on hex grids. The line is sampled once per cell it crosses, nudged to one side to settle
+// samples on an edge between two cells; if corners is set, it is nudged to the other side too and both are visited.
+func (grid *Grid) walkHexLine(a, b pair.Pair, corners bool, visit func(p pair.Pair) bool) bool {
+	const nudge = 1e-6
+
+	n := a.HexDist(b)
+	from, to := a.Axial(), b.Axial()
+	last := a
+	if !visit(a) {
+		return false
+	}
+
+	for k := 1; k <= n; k++ {
+		t := float64(k) / float64(n)
+		r := float64(from.I) + float64(to.I-from.I)*t
+		q := float64(from.J) + float64(to.J-from.J)*t
+
+		samples := []pair.Pair{pair.HexRound(r+nudge, q+nudge).Offset()}
+		if corners {
+			samples = append(samples, pair.HexRound(r-nudge, q-nudge).Offset())
+		}
+
+		for _, p := range samples {
+			if p != last {
+				if !visit(p) {
+					return false
+				}
+				last = p
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,332 @@
+package solver
+
+import (
+	"container/heap"
+	"math"
+	"pathfinding/pair"
+)
+
+// Side of the square clusters HPA* splits the grid into.
+const HPA_CLUSTER_SIZE = 10
+
+// Longest run of open cells along a cluster border crossed by a single entrance. Longer runs get one at each end.
+const HPA_MAX_ENTRANCE_WIDTH = 6
+
+// A HierarchicalSolver searches an abstract graph over clusters of the grid before refining it into a path.
+type HierarchicalSolver interface {
+	Solver
+	// ClusterSize returns the side of the square clusters the grid is split into.
+	ClusterSize() int
+	// AbstractEdges returns the edges of the abstract graph, each between two of its cells.
+	AbstractEdges() [][2]pair.Pair
+}
+
+// absEdge is an edge of the abstract graph, with the cells of the path it stands for.
+type absEdge struct {
+	to   pair.Pair
+	cost float64
+	path []*Node // Cells after the origin of the edge, up to to
+}
+
+// hpastar is Hierarchical Pathfinding A*. Init finds the entrances between clusters and links the ones of each
+// cluster with their shortest paths inside it; Start and End are linked the same way. Each step expands a node
+// of that abstract graph with A*, and Result refines the abstract path back into cells.
+type hpastar struct {
+	grid  *Grid
+	edges map[pair.Pair][]absEdge
+
+	gcost  map[pair.Pair]float64
+	prev   map[pair.Pair]*absEdge
+	from   map[pair.Pair]pair.Pair
+	closed map[pair.Pair]bool
+	open   frontier
+	seq    int
+}
+
+func init() {
+	Register("HPA*", func() Solver { return &hpastar{} })
+}
+
+func (h *hpastar) Name() string {
+	return "HPA*"
+}
+
+func (h *hpastar) ClusterSize() int {
+	return HPA_CLUSTER_SIZE
+}
+
+func (h *hpastar) AbstractEdges() [][2]pair.Pair {
+	var edges [][2]pair.Pair
+	for from, list := range h.edges {
+		for _, e := range list {
+			if from.I < e.to.I || from.I == e.to.I && from.J < e.to.J {
+				edges = append(edges, [2]pair.Pair{from, e.to})
+			}
+		}
+	}
+	return edges
+}
+
+func (h *hpastar) Init(grid *Grid) {
+	h.grid = grid
+	h.edges = map[pair.Pair][]absEdge{}
+
+	// Abstract nodes of every cluster, by the coordinates of its top left cell
+	nodes := map[pair.Pair][]*Node{}
+	addNode := func(n *Node) {
+		cluster := h.cluster(n.Coord)
+		for _, other := range nodes[cluster] {
+			if other == n {
+				return
+			}
+		}
+		nodes[cluster] = append(nodes[cluster], n)
+	}
+	link := func(a, b *Node) {
+		addNode(a)
+		addNode(b)
+		h.edges[a.Coord] = append(h.edges[a.Coord], absEdge{to: b.Coord, cost: grid.g(*a, *b), path: []*Node{b}})
+		h.edges[b.Coord] = append(h.edges[b.Coord], absEdge{to: a.Coord, cost: grid.g(*b, *a), path: []*Node{a}})
+	}
+
+	// Moves between cells of different clusters, by the pair of clusters they cross between, which may only
+	// touch at a corner. Every move is found from the cell of the first cluster of the pair. They come from
+	// Neighbors rather than facing cells, as diagonals and the offset rows of hex grids cross borders on the slant.
+	var borders [][2]pair.Pair
+	crossings := map[[2]pair.Pair][][2]*Node{}
+	for i := range grid.Cells {
+		for j := range grid.Cells[i] {
+			a := &grid.Cells[i][j]
+			if a.IsWall {
+				continue
+			}
+			for _, b := range grid.Neighbors(a) {
+				border := [2]pair.Pair{h.cluster(a.Coord), h.cluster(b.Coord)}
+				if border[0].I > border[1].I || border[0].I == border[1].I && border[0].J >= border[1].J {
+					continue
+				}
+				if crossings[border] == nil {
+					borders = append(borders, border)
+				}
+				crossings[border] = append(crossings[border], [2]*Node{a, b})
+			}
+		}
+	}
+	for _, border := range borders {
+		h.entrances(crossings[border], link)
+	}
+
+	addNode(grid.Start)
+	addNode(grid.End)
+
+	for cluster, list := range nodes {
+		for _, a := range list {
+			dist, prev := h.clusterPaths(a, cluster)
+			for _, b := range list {
+				cost := dist[b.Coord.I-cluster.I][b.Coord.J-cluster.J]
+				if a == b || math.IsInf(cost, 1) {
+					continue
+				}
+
+				var path []*Node
+				for n := b; n != a; n = prev[n.Coord.I-cluster.I][n.Coord.J-cluster.J] {
+					path = append(path, n)
+				}
+				for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+					path[i], path[j] = path[j], path[i]
+				}
+				h.edges[a.Coord] = append(h.edges[a.Coord], absEdge{to: b.Coord, cost: cost, path: path})
+			}
+		}
+	}
+
+	h.gcost = map[pair.Pair]float64{grid.Start.Coord: 0}
+	h.prev = map[pair.Pair]*absEdge{}
+	h.from = map[pair.Pair]pair.Pair{}
+	h.closed = map[pair.Pair]bool{}
+	h.open = frontier{{node: grid.Start, f: grid.h(*grid.Start)}}
+	grid.Start.Added = true
+}
+
+// entrances links the crossings between two clusters, each a move from a cell of one to a cell of the other.
+// Crossings whose cells are joined along the border on both sides make up an entrance, which gets a single link
+// in its middle, or one at each end if it is wider than HPA_MAX_ENTRANCE_WIDTH.
+func (h *hpastar) entrances(crossings [][2]*Node, link func(a, b *Node)) {
+	// Runs of cells along the border, joined by moves that stay in their cluster
+	runs := map[*Node]*Node{}
+	var run func(n *Node) *Node
+	run = func(n *Node) *Node {
+		if runs[n] != n {
+			runs[n] = run(runs[n])
+		}
+		return runs[n]
+	}
+	for _, crossing := range crossings {
+		for _, n := range crossing {
+			runs[n] = n
+		}
+	}
+	for _, crossing := range crossings {
+		for _, n := range crossing {
+			for _, m := range h.grid.Neighbors(n) {
+				if _, onBorder := runs[m]; onBorder && h.cluster(m.Coord) == h.cluster(n.Coord) {
+					runs[run(m)] = run(n)
+				}
+			}
+		}
+	}
+
+	// Entrances in the order their first crossing was found, with the cells they leave the first cluster from
+	var entrances [][][2]*Node
+	var widths []map[*Node]bool
+	index := map[[2]*Node]int{}
+	for _, crossing := range crossings {
+		key := [2]*Node{run(crossing[0]), run(crossing[1])}
+		k, found := index[key]
+		if !found {
+			k = len(entrances)
+			index[key] = k
+			entrances = append(entrances, nil)
+			widths = append(widths, map[*Node]bool{})
+		}
+		entrances[k] = append(entrances[k], crossing)
+		widths[k][crossing[0]] = true
+	}
+
+	for k, entrance := range entrances {
+		if len(widths[k]) <= HPA_MAX_ENTRANCE_WIDTH {
+			middle := entrance[len(entrance)/2]
+			link(middle[0], middle[1])
+		} else {
+			first, last := entrance[0], entrance[len(entrance)-1]
+			link(first[0], first[1])
+			link(last[0], last[1])
+		}
+	}
+}
+
+// cluster returns the top left cell of the cluster p is in.
+func (h *hpastar) cluster(p pair.Pair) pair.Pair {
+	return pair.New(p.I/HPA_CLUSTER_SIZE*HPA_CLUSTER_SIZE, p.J/HPA_CLUSTER_SIZE*HPA_CLUSTER_SIZE)
+}
+
+// clusterPaths runs Dijkstra from a without leaving its cluster, returning the cost of reaching each cell
+// (infinite if it can not) and the cell each one is reached from, indexed by their position in the cluster.
+func (h *hpastar) clusterPaths(a *Node, cluster pair.Pair) ([][]float64, [][]*Node) {
+	rows := min(HPA_CLUSTER_SIZE, len(h.grid.Cells)-cluster.I)
+	cols := min(HPA_CLUSTER_SIZE, len(h.grid.Cells[0])-cluster.J)
+	dist, prev := make([][]float64, rows), make([][]*Node, rows)
+	for i := range dist {
+		dist[i], prev[i] = make([]float64, cols), make([]*Node, cols)
+		for j := range dist[i] {
+			dist[i][j] = math.Inf(1)
+		}
+	}
+
+	dist[a.Coord.I-cluster.I][a.Coord.J-cluster.J] = 0
+	open := frontier{{node: a}}
+	seq := 0
+	for open.Len() > 0 {
+		e := heap.Pop(&open).(frontierEntry)
+		if e.f > dist[e.node.Coord.I-cluster.I][e.node.Coord.J-cluster.J] {
+			continue
+		}
+
+		for _, n := range h.grid.Neighbors(e.node) {
+			i, j := n.Coord.I-cluster.I, n.Coord.J-cluster.J
+			if i < 0 || i >= rows || j < 0 || j >= cols {
+				continue
+			}
+
+			if alt := e.f + h.grid.g(*e.node, *n); alt < dist[i][j] {
+				dist[i][j] = alt
+				prev[i][j] = e.node
+				seq++
+				heap.Push(&open, frontierEntry{node: n, f: alt, seq: seq})
+			}
+		}
+	}
+
+	return dist, prev
+}
+
+func (h *hpastar) Step() StepResult {
+	var u *Node
+	for h.open.Len() > 0 {
+		e := heap.Pop(&h.open).(frontierEntry)
+		if !h.closed[e.node.Coord] {
+			u = e.node
+			break
+		}
+	}
+	if u == nil {
+		return StepResult{Done: true}
+	}
+
+	h.closed[u.Coord] = true
+	u.Visited = true
+
+	if u == h.grid.End {
+		return StepResult{Popped: u, Done: true}
+	}
+
+	var pushed []*Node
+	for k := range h.edges[u.Coord] {
+		e := &h.edges[u.Coord][k]
+		if h.closed[e.to] {
+			continue
+		}
+
+		alt := h.gcost[u.Coord] + e.cost
+		if old, seen := h.gcost[e.to]; !seen || alt < old {
+			h.gcost[e.to] = alt
+			h.prev[e.to] = e
+			h.from[e.to] = u.Coord
+
+			n := &h.grid.Cells[e.to.I][e.to.J]
+			n.Added = true
+			h.seq++
+			heap.Push(&h.open, frontierEntry{node: n, f: alt + h.grid.h(*n), f2: h.grid.h(*n), seq: h.seq})
+			pushed = append(pushed, n)
+		}
+	}
+
+	return StepResult{Popped: u, Pushed: pushed, Done: h.open.Len() == 0}
+}
+
+// Result refines the abstract path into the cells of the edges it is made of, and builds the path from them.
+func (h *hpastar) Result() Status {
+	if !h.closed[h.grid.End.Coord] {
+		return STATUS_END_NOPATH
+	}
+
+	var edges []*absEdge
+	for p := h.grid.End.Coord; p != h.grid.Start.Coord; p = h.from[p] {
+		edges = append(edges, h.prev[p])
+	}
+
+	cells := []*Node{h.grid.Start}
+	index := map[*Node]int{h.grid.Start: 0}
+	for k := len(edges) - 1; k >= 0; k-- {
+		for _, n := range edges[k].path {
+			// Erase the loop if refined edges cross back over a cell, which ties in the abstract costs allow
+			if at, seen := index[n]; seen {
+				for _, erased := range cells[at+1:] {
+					delete(index, erased)
+				}
+				cells = cells[:at+1]
+				continue
+			}
+			index[n] = len(cells)
+			cells = append(cells, n)
+		}
+	}
+
+	h.grid.Start.Prev = nil
+	for k := 1; k < len(cells); k++ {
+		cells[k].Prev = cells[k-1]
+	}
+
+	h.grid.constructPath()
+	return STATUS_END_SUCCESS
+}
@@ -0,0 +1,110 @@
+package solver
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"pathfinding/pair"
+)
+
+// Lightness under which image cells are walls, from 0 for black to 1 for white. Exported canvases draw roads in
+// dark grey between black gaps, which must still read as open.
+const IMAGE_WALL_LIGHTNESS = 0.2
+
+// Lightness under which image cells get each weight when reading terrain, the lighter ones being roads.
+var imageTerrainLightness = []struct {
+	lightness float64
+	weight    int
+}{{IMAGE_WALL_LIGHTNESS, -1}, {0.4, WEIGHT_WATER}, {0.6, WEIGHT_MUD}, {0.8, WEIGHT_GRASS}} // -1 is a wall
+
+// GridFromImage returns a rows*cols grid drawn by img, each cell taking the pixels of its share of the image,
+// or the pixel under its centre if the image is too small for it to have any.
+// Cells mostly dark are walls, and if terrain is set, grey ones get heavier weights the darker they are.
+// Start and End go on the cells with the most green and red pixels, or on the open cells nearest to the
+// bottom left and top right corners if there are none.
+func GridFromImage(img image.Image, rows, cols int, terrain bool) (Grid, error) {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return Grid{}, fmt.Errorf("image is empty")
+	}
+
+	grid := NewGrid(rows, cols, pair.New(rows-1, 0), pair.New(0, cols-1))
+	var start, end *Node
+	mostGreen, mostRed := 0, 0
+
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			x0, x1 := bounds.Min.X+j*bounds.Dx()/cols, bounds.Min.X+(j+1)*bounds.Dx()/cols
+			y0, y1 := bounds.Min.Y+i*bounds.Dy()/rows, bounds.Min.Y+(i+1)*bounds.Dy()/rows
+			if x0 == x1 {
+				x0 = bounds.Min.X + (2*j+1)*bounds.Dx()/(2*cols)
+				x1 = x0 + 1
+			}
+			if y0 == y1 {
+				y0 = bounds.Min.Y + (2*i+1)*bounds.Dy()/(2*rows)
+				y1 = y0 + 1
+			}
+
+			green, red, grey := 0, 0, 0
+			lightness := 0.0
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+					r, g, b := int(c.R), int(c.G), int(c.B)
+					if g > 128 && g > r+64 && g > b+64 {
+						green++
+					} else if r > 128 && r > g+64 && r > b+64 {
+						red++
+					} else {
+						grey++
+						lightness += (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 255
+					}
+				}
+			}
+
+			node := &grid.Cells[i][j]
+			if grey > 0 {
+				lightness /= float64(grey)
+				node.IsWall = lightness < IMAGE_WALL_LIGHTNESS
+				if terrain {
+					node.IsWall = false
+					for _, level := range imageTerrainLightness {
+						if lightness < level.lightness {
+							node.IsWall = level.weight == -1
+							if !node.IsWall {
+								node.Weight = level.weight
+							}
+							break
+						}
+					}
+				}
+			}
+
+			if green > mostGreen {
+				start, mostGreen = node, green
+			}
+			if red > mostRed {
+				end, mostRed = node, red
+			}
+		}
+	}
+
+	// Flags are on open cells, whatever the pixels around them were
+	for _, flag := range []*Node{start, end} {
+		if flag != nil {
+			flag.IsWall, flag.Weight = false, BASE_WEIGHT
+		}
+	}
+	if start == nil {
+		start = grid.nearestOpen(pair.New(rows-1, 0), end)
+	}
+	if end == nil {
+		end = grid.nearestOpen(pair.New(0, cols-1), start)
+	}
+	if start == nil || end == nil || start == end {
+		return Grid{}, fmt.Errorf("image has less than two open cells")
+	}
+
+	grid.Start, grid.End = start, end
+	return grid, nil
+}
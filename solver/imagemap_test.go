@@ -0,0 +1,63 @@
+package solver
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"pathfinding/pair"
+	"testing"
+)
+
+// Images with fewer pixels than the grid has cells are scaled up: every cell takes the pixel under its centre.
+func TestGridFromSmallImage(t *testing.T) {
+	rng := rand.New(rand.NewSource(8))
+	const rows, cols = 6, 9
+
+	img := image.NewRGBA(image.Rect(0, 0, cols, rows))
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			img.Set(x, y, color.White)
+			if rng.Intn(3) == 0 {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+	img.Set(0, rows-1, color.RGBA{0, 255, 0, 255})
+	img.Set(cols-1, 0, color.RGBA{255, 0, 0, 255})
+
+	// Through PNG, as images are loaded
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grid, err := GridFromImage(decoded, 2*rows, 3*cols, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range grid.Cells {
+		for j, node := range grid.Cells[i] {
+			p := pair.New(i/2, j/3)
+			if grid.IsFlag(node.Coord) {
+				continue
+			}
+			if wall := img.RGBAAt(p.J, p.I) == (color.RGBA{0, 0, 0, 255}); node.IsWall != wall {
+				t.Fatalf("cell %v read as wall %t, its pixel %v is wall %t", node.Coord, node.IsWall, p, wall)
+			}
+		}
+	}
+
+	// The first of the cells showing the flag pixel gets it
+	if want := pair.New(2*(rows-1), 0); grid.Start.Coord != want {
+		t.Fatalf("start at %v, want %v", grid.Start.Coord, want)
+	}
+	if want := pair.New(0, 3*(cols-1)); grid.End.Coord != want {
+		t.Fatalf("end at %v, want %v", grid.End.Coord, want)
+	}
+}
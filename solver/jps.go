@@ -0,0 +1,265 @@
+package solver
+
+import (
+	"container/heap"
+	"math"
+	"pathfinding/pair"
+)
+
+// jps is Jump Point Search. It treats every cell as having the same weight, which is what lets it skip over
+// the symmetric paths A* expands one by one, with pruning rules for each square Neighborhood.
+// Hex grids have no such rules, so there every neighbour is a successor, as in A* with uniform weights.
+type jps struct {
+	grid *Grid
+	pq   PriorityQueue
+}
+
+func init() {
+	Register("Jump Point Search", func() Solver { return &jps{} })
+}
+
+func (s *jps) Name() string {
+	return "Jump Point Search"
+}
+
+func (s *jps) Init(grid *Grid) {
+	s.grid = grid
+
+	for i := range grid.Cells {
+		for j := range grid.Cells[i] {
+			grid.Cells[i][j].Gcost = math.MaxFloat64
+			grid.Cells[i][j].Cost = math.MaxFloat64
+		}
+	}
+
+	grid.Start.Gcost = 0
+	grid.Start.Cost = grid.h(*grid.Start)
+	grid.Start.IsJumpPoint = true
+
+	s.pq = PriorityQueue{grid.Start}
+	heap.Init(&s.pq)
+}
+
+func (s *jps) Step() StepResult {
+	if s.pq.Len() == 0 {
+		return StepResult{Done: true}
+	}
+
+	current := heap.Pop(&s.pq).(*Node)
+	current.Visited = true
+
+	if current == s.grid.End {
+		return StepResult{Popped: current, Done: true}
+	}
+
+	var successors []*Node
+	if s.grid.Neighborhood == NEIGHBORHOOD_HEX {
+		successors = s.grid.Neighbors(current)
+	} else {
+		for _, dir := range s.directions(current) {
+			if !s.canMove(current.Coord, dir) {
+				continue
+			}
+			if jumpPoint := s.jump(current.Coord.Add(dir), dir); jumpPoint != nil {
+				successors = append(successors, jumpPoint)
+			}
+		}
+	}
+
+	var pushed []*Node
+	for _, jumpPoint := range successors {
+		if jumpPoint.Visited {
+			continue
+		}
+
+		gcost := current.Gcost + s.distance(current.Coord, jumpPoint.Coord)
+		if gcost < jumpPoint.Gcost {
+			jumpPoint.Prev = current
+			jumpPoint.Gcost = gcost
+			jumpPoint.Cost = gcost + s.grid.h(*jumpPoint)
+			jumpPoint.IsJumpPoint = true
+
+			if jumpPoint.Added {
+				heap.Fix(&s.pq, jumpPoint.index)
+			} else {
+				jumpPoint.Added = true
+				heap.Push(&s.pq, jumpPoint)
+			}
+			pushed = append(pushed, jumpPoint)
+		}
+	}
+
+	return StepResult{Popped: current, Pushed: pushed, Done: s.pq.Len() == 0}
+}
+
+// directions returns the pruned set of directions worth jumping towards from n, given where it was reached from.
+// Some may be blocked, which Step checks with canMove.
+func (s *jps) directions(n *Node) []pair.Pair {
+	if n.Prev == nil {
+		return []pair.Pair{pair.Up(), pair.Down(), pair.Left(), pair.Right(), pair.UpLeft(), pair.UpRight(), pair.DownLeft(), pair.DownRight()}
+	}
+
+	d := pair.New(sign(n.Coord.I-n.Prev.Coord.I), sign(n.Coord.J-n.Prev.Coord.J))
+	p := n.Coord
+	dirs := []pair.Pair{d}
+
+	switch {
+	case s.grid.Neighborhood == NEIGHBORHOOD_4:
+		// Jump points only turn where something was found to the side, so both turns are worth it
+		dirs = append(dirs, d.TurnL(), d.TurnR())
+
+	case d.I != 0 && d.J != 0:
+		dirs = append(dirs, pair.New(d.I, 0), pair.New(0, d.J))
+		// Cutting corners, a wall behind a side forces the diagonal past it
+		if s.grid.Neighborhood == NEIGHBORHOOD_8 {
+			if !s.grid.walkable(p.Sub(pair.New(d.I, 0))) {
+				dirs = append(dirs, pair.New(-d.I, d.J))
+			}
+			if !s.grid.walkable(p.Sub(pair.New(0, d.J))) {
+				dirs = append(dirs, pair.New(d.I, -d.J))
+			}
+		}
+
+	case s.grid.Neighborhood == NEIGHBORHOOD_8:
+		// Moving straight, a wall at a side forces the diagonal past it
+		for _, side := range []pair.Pair{d.TurnL(), d.TurnR()} {
+			if !s.grid.walkable(p.Add(side)) {
+				dirs = append(dirs, d.Add(side))
+			}
+		}
+
+	default:
+		// Moving straight without cutting corners, a side is forced when the wall behind it kept the diagonal
+		// from reaching it, and so is the diagonal past it
+		for _, side := range []pair.Pair{d.TurnL(), d.TurnR()} {
+			if !s.grid.walkable(p.Add(side).Sub(d)) {
+				dirs = append(dirs, side, d.Add(side))
+			}
+		}
+	}
+	return dirs
+}
+
+// jump moves from p in direction dir until it finds the end, a cell with forced neighbours, or a dead end (nil).
+func (s *jps) jump(p, dir pair.Pair) *Node {
+	for {
+		if !s.grid.walkable(p) {
+			return nil
+		}
+
+		node := &s.grid.Cells[p.I][p.J]
+		if node == s.grid.End {
+			return node
+		}
+
+		if s.forced(p, dir) {
+			return node
+		}
+
+		// Diagonals, and columns on 4-way grids, stop where the straight jumps from them find something
+		if dir.I != 0 && dir.J != 0 {
+			if s.jump(p.Add(pair.New(dir.I, 0)), pair.New(dir.I, 0)) != nil || s.jump(p.Add(pair.New(0, dir.J)), pair.New(0, dir.J)) != nil {
+				return node
+			}
+		} else if s.grid.Neighborhood == NEIGHBORHOOD_4 && dir.I != 0 {
+			if s.jump(p.Add(pair.Right()), pair.Right()) != nil || s.jump(p.Add(pair.Left()), pair.Left()) != nil {
+				return node
+			}
+		}
+
+		if !s.canMove(p, dir) {
+			return nil
+		}
+		p = p.Add(dir)
+	}
+}
+
+// forced reports whether p, reached moving in direction dir, has neighbours only reached optimally through it.
+func (s *jps) forced(p, dir pair.Pair) bool {
+	switch {
+	case dir.I != 0 && dir.J != 0:
+		if s.grid.Neighborhood != NEIGHBORHOOD_8 {
+			return false
+		}
+		return s.grid.walkable(p.Add(pair.New(-dir.I, dir.J))) && !s.grid.walkable(p.Sub(pair.New(dir.I, 0))) ||
+			s.grid.walkable(p.Add(pair.New(dir.I, -dir.J))) && !s.grid.walkable(p.Sub(pair.New(0, dir.J)))
+
+	case s.grid.Neighborhood == NEIGHBORHOOD_8:
+		for _, side := range []pair.Pair{dir.TurnL(), dir.TurnR()} {
+			if s.grid.walkable(p.Add(dir).Add(side)) && !s.grid.walkable(p.Add(side)) {
+				return true
+			}
+		}
+
+	default:
+		for _, side := range []pair.Pair{dir.TurnL(), dir.TurnR()} {
+			if s.grid.walkable(p.Add(side)) && !s.grid.walkable(p.Add(side).Sub(dir)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// canMove reports whether a single step from p in direction dir is allowed by the grid Neighborhood.
+func (s *jps) canMove(p, dir pair.Pair) bool {
+	if !s.grid.walkable(p.Add(dir)) {
+		return false
+	}
+	if dir.I != 0 && dir.J != 0 {
+		switch s.grid.Neighborhood {
+		case NEIGHBORHOOD_4:
+			return false
+		case NEIGHBORHOOD_8_NO_CORNERS:
+			return s.grid.walkable(p.Add(pair.New(dir.I, 0))) && s.grid.walkable(p.Add(pair.New(0, dir.J)))
+		}
+	}
+	return true
+}
+
+// Result fills in the cells between consecutive jump points, so Prev links adjacent cells as in every other solver.
+func (s *jps) Result() Status {
+	if s.grid.End.Prev != nil {
+		for node := s.grid.End; node.Prev != nil; {
+			jumpPoint := node.Prev
+			dir := pair.New(sign(jumpPoint.Coord.I-node.Coord.I), sign(jumpPoint.Coord.J-node.Coord.J))
+			for p := node.Coord.Add(dir); !p.Eq(jumpPoint.Coord); p = p.Add(dir) {
+				between := &s.grid.Cells[p.I][p.J]
+				node.Prev = between
+				node = between
+			}
+			node.Prev = jumpPoint
+			node = jumpPoint
+		}
+	}
+
+	if !s.grid.constructPath() {
+		return STATUS_END_NOPATH
+	}
+	return STATUS_END_SUCCESS
+}
+
+// distance returns the length of the shortest move between a and b on an empty grid.
+func (s *jps) distance(a, b pair.Pair) float64 {
+	if s.grid.Neighborhood == NEIGHBORHOOD_HEX {
+		return BASE_WEIGHT * float64(a.HexDist(b))
+	}
+	return octile(a, b)
+}
+
+// octile returns the length of the shortest 8-way move between a and b on an empty grid.
+func octile(a, b pair.Pair) float64 {
+	dy := math.Abs(float64(a.I - b.I))
+	dx := math.Abs(float64(a.J - b.J))
+	return BASE_WEIGHT * (dx + dy + (math.Sqrt2-2)*math.Min(dx, dy))
+}
+
+func sign(x int) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,221 @@
+package solver
+
+import (
+	"container/heap"
+	"math"
+	"pathfinding/pair"
+)
+
+// lpa is Lifelong Planning A*, which keeps its search state after finding a path so that, when cells
+// change, only the part of the search they affect has to be expanded again.
+// As D* Lite it searches from End to Start instead.
+type lpa struct {
+	name    string
+	reverse bool // Search from End towards Start (D* Lite)
+
+	grid   *Grid
+	g, rhs [][]float64
+	open   frontier
+	seq    int
+}
+
+func init() {
+	Register("LPA*", func() Solver { return &lpa{name: "LPA*"} })
+	Register("D* Lite", func() Solver { return &lpa{name: "D* Lite", reverse: true} })
+}
+
+func (l *lpa) Name() string {
+	return l.name
+}
+
+func (l *lpa) Init(grid *Grid) {
+	l.grid = grid
+	l.open = nil
+
+	l.g = make([][]float64, len(grid.Cells))
+	l.rhs = make([][]float64, len(grid.Cells))
+	for i := range grid.Cells {
+		l.g[i] = make([]float64, len(grid.Cells[i]))
+		l.rhs[i] = make([]float64, len(grid.Cells[i]))
+		for j := range l.g[i] {
+			l.g[i][j] = math.MaxFloat64
+			l.rhs[i][j] = math.MaxFloat64
+		}
+	}
+
+	source := l.source()
+	l.rhs[source.Coord.I][source.Coord.J] = 0
+	l.push(source)
+}
+
+// source is the cell the search grows from, and target the one it searches for.
+func (l *lpa) source() *Node {
+	if l.reverse {
+		return l.grid.End
+	}
+	return l.grid.Start
+}
+
+func (l *lpa) target() *Node {
+	if l.reverse {
+		return l.grid.Start
+	}
+	return l.grid.End
+}
+
+// key returns the two level priority of n: the estimated path cost through it, then its cost from the source.
+func (l *lpa) key(n *Node) (float64, float64) {
+	best := math.Min(l.g[n.Coord.I][n.Coord.J], l.rhs[n.Coord.I][n.Coord.J])
+	if best == math.MaxFloat64 {
+		return math.MaxFloat64, math.MaxFloat64
+	}
+	return best + l.grid.hTo(*n, *l.target()), best
+}
+
+// cost returns the cost of the move between adjacent cells, from the one nearer the source to the other one.
+func (l *lpa) cost(near, far *Node) float64 {
+	if l.reverse {
+		return l.grid.g(*far, *near)
+	}
+	return l.grid.g(*near, *far)
+}
+
+func (l *lpa) push(n *Node) {
+	k1, k2 := l.key(n)
+	l.seq++
+	heap.Push(&l.open, frontierEntry{node: n, f: k1, f2: k2, seq: l.seq})
+
+	if !n.Added {
+		n.Added = true
+		n.FromEnd = l.reverse
+	}
+}
+
+func (l *lpa) consistent(n *Node) bool {
+	return l.g[n.Coord.I][n.Coord.J] == l.rhs[n.Coord.I][n.Coord.J]
+}
+
+// updateVertex recomputes the rhs of n from its neighbours and queues it if it became inconsistent.
+// Entries left in the queue for nodes that changed since are stale, and skipped when they reach the top.
+func (l *lpa) updateVertex(n *Node) {
+	i, j := n.Coord.I, n.Coord.J
+	if n != l.source() {
+		l.rhs[i][j] = math.MaxFloat64
+		if !n.IsWall {
+			for _, neighbor := range l.grid.Neighbors(n) {
+				ni, nj := neighbor.Coord.I, neighbor.Coord.J
+				if l.g[ni][nj] != math.MaxFloat64 {
+					l.rhs[i][j] = math.Min(l.rhs[i][j], l.g[ni][nj]+l.cost(neighbor, n))
+				}
+			}
+		}
+	}
+
+	if !l.consistent(n) {
+		l.push(n)
+	}
+}
+
+// clean drops the entries at the top of the queue whose node is consistent, and requeues
+// those whose key is outdated, so the top entry is the next node to expand.
+func (l *lpa) clean() {
+	for l.open.Len() > 0 {
+		top := l.open[0]
+		if !l.consistent(top.node) {
+			if k1, k2 := l.key(top.node); top.f == k1 && top.f2 == k2 {
+				return
+			}
+		}
+
+		heap.Pop(&l.open)
+		if !l.consistent(top.node) {
+			l.push(top.node)
+		}
+	}
+}
+
+// done reports whether no node left in the queue can lower the cost of the path to the target.
+// Nodes whose key ties with the target one are expanded too, as rounding may hide they are on the path.
+func (l *lpa) done() bool {
+	l.clean()
+
+	target := l.target()
+	t1, _ := l.key(target)
+	return l.open.Len() == 0 || l.open[0].f > t1+1e-9 && l.consistent(target)
+}
+
+func (l *lpa) Step() StepResult {
+	if l.done() {
+		return StepResult{Done: true}
+	}
+
+	u := heap.Pop(&l.open).(frontierEntry).node
+	i, j := u.Coord.I, u.Coord.J
+	u.Visited = true
+
+	var pushed []*Node
+	if l.g[i][j] > l.rhs[i][j] {
+		l.g[i][j] = l.rhs[i][j]
+	} else {
+		l.g[i][j] = math.MaxFloat64
+		l.updateVertex(u)
+	}
+
+	for _, neighbor := range l.grid.Neighbors(u) {
+		l.updateVertex(neighbor)
+		if !l.consistent(neighbor) {
+			pushed = append(pushed, neighbor)
+		}
+	}
+
+	return StepResult{Popped: u, Pushed: pushed, Done: l.done()}
+}
+
+// CellChanged updates the search after the wall or weight of n changed, affecting the moves into and out of it.
+func (l *lpa) CellChanged(n *Node) {
+	l.updateVertex(n)
+	for _, dir := range []pair.Pair{pair.Up(), pair.Down(), pair.Left(), pair.Right(), pair.UpLeft(), pair.UpRight(), pair.DownLeft(), pair.DownRight()} {
+		p := n.Coord.Add(dir)
+		if p.InBounds(0, 0, len(l.grid.Cells), len(l.grid.Cells[0])) {
+			l.updateVertex(&l.grid.Cells[p.I][p.J])
+		}
+	}
+}
+
+// Result follows the cheapest consistent neighbours back to the source to set Prev along the path.
+func (l *lpa) Result() Status {
+	target := l.target()
+	l.grid.Start.Prev = nil
+	l.grid.End.Prev = nil // Left over from the path found before a replan
+	if l.g[target.Coord.I][target.Coord.J] != math.MaxFloat64 && l.consistent(target) {
+		for node := target; node != l.source(); {
+			var next *Node
+			best := math.MaxFloat64
+			for _, neighbor := range l.grid.Neighbors(node) {
+				// Nodes left inconsistent may have an outdated g, lower than their real cost
+				c := l.g[neighbor.Coord.I][neighbor.Coord.J]
+				if l.consistent(neighbor) && c < l.g[node.Coord.I][node.Coord.J] && c+l.cost(neighbor, node) < best {
+					best = c + l.cost(neighbor, node)
+					next = neighbor
+				}
+			}
+
+			if next == nil {
+				l.grid.End.Prev = nil
+				break
+			}
+
+			if l.reverse {
+				next.Prev = node
+			} else {
+				node.Prev = next
+			}
+			node = next
+		}
+	}
+
+	if !l.grid.constructPath() {
+		return STATUS_END_NOPATH
+	}
+	return STATUS_END_SUCCESS
+}
@@ -0,0 +1,439 @@
+package solver
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"math"
+	"pathfinding/pair"
+	"time"
+)
+
+// A Task is the start and end of one unit, in multi-agent searches.
+type Task struct {
+	Start *Node
+	End   *Node
+}
+
+// A Track is the cell a unit is on at every time step, from its start until it stays on its end for good.
+type Track []pair.Pair
+
+// At returns the cell of the unit at time t. Units wait on their end once they reach it.
+func (tr Track) At(t int) pair.Pair {
+	if t >= len(tr) {
+		return tr[len(tr)-1]
+	}
+	return tr[t]
+}
+
+// Planner is the algorithm used to find collision free tracks for every task of a grid.
+type Planner string
+
+const (
+	PLANNER_PRIORITIZED Planner = "PLANNER_PRIORITIZED" // Plan each unit in turn, avoiding the tracks of the previous ones
+	PLANNER_CBS         Planner = "PLANNER_CBS"         // Conflict-Based Search, optimal in the sum of the track costs
+)
+
+// spaceTime is a cell at a time step.
+type spaceTime struct {
+	p pair.Pair
+	t int
+}
+
+// A constraint forbids a unit to be on a cell at a time, or if from is set, to move from it to the cell at that time.
+type constraint struct {
+	unit int
+	at   spaceTime
+	from pair.Pair
+	move bool
+}
+
+// reservations are the moves a unit can not make while planning its track.
+type reservations struct {
+	vertex map[spaceTime]bool
+	edge   map[[2]spaceTime]bool
+	parked map[pair.Pair]int // Cells taken for good from a time on, by units that reached their end
+	latest int               // Last time step with a reservation
+}
+
+func newReservations() *reservations {
+	return &reservations{vertex: map[spaceTime]bool{}, edge: map[[2]spaceTime]bool{}, parked: map[pair.Pair]int{}}
+}
+
+// add reserves the cell p at time t.
+func (r *reservations) add(p pair.Pair, t int) {
+	r.vertex[spaceTime{p, t}] = true
+	r.latest = max(r.latest, t)
+}
+
+// addMove reserves the move from a to b arriving at time t.
+func (r *reservations) addMove(a, b pair.Pair, t int) {
+	r.edge[[2]spaceTime{{a, t - 1}, {b, t}}] = true
+	r.latest = max(r.latest, t)
+}
+
+// blocked reports whether moving (or waiting) from a to b, arriving at time t, is reserved.
+func (r *reservations) blocked(a, b pair.Pair, t int) bool {
+	if since, ok := r.parked[b]; ok && t >= since {
+		return true
+	}
+	return r.vertex[spaceTime{b, t}] || r.edge[[2]spaceTime{{a, t - 1}, {b, t}}]
+}
+
+// canStay reports whether a unit can stay on p for good from time t on.
+func (r *reservations) canStay(p pair.Pair, t int) bool {
+	if _, ok := r.parked[p]; ok {
+		return false
+	}
+	for later := t + 1; later <= r.latest; later++ {
+		if r.vertex[spaceTime{p, later}] {
+			return false
+		}
+	}
+	return true
+}
+
+// stNode is a state of the space-time search of a track.
+type stNode struct {
+	at   spaceTime
+	g, f int
+	prev *stNode
+}
+
+// stQueue is a priority queue of space-time states by f, preferring later states among equal ones.
+type stQueue []*stNode
+
+func (q stQueue) Len() int { return len(q) }
+func (q stQueue) Less(i, j int) bool {
+	if q[i].f != q[j].f {
+		return q[i].f < q[j].f
+	}
+	return q[i].at.t > q[j].at.t
+}
+func (q stQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *stQueue) Push(x interface{}) { *q = append(*q, x.(*stNode)) }
+func (q *stQueue) Pop() interface{} {
+	old := *q
+	node := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return node
+}
+
+// errIterationLimit is returned by a multi-agent search that reached Options.MaxIterations.
+var errIterationLimit = errors.New("iteration limit reached")
+
+// multi searches collision free tracks for the tasks of a grid. Every move, waiting included, takes one time step.
+type multi struct {
+	grid *Grid
+	ctx  context.Context
+	opts Options
+}
+
+// PlanMulti searches a track for every task of grid with planner, so that no two units are ever on the same cell
+// or swap cells in the same time step. The tracks are stored in grid.Tracks, and the path length and cost are the
+// sum of the time steps every unit takes to reach its end. Iterations count the space-time states expanded.
+func PlanMulti(ctx context.Context, grid *Grid, planner Planner, opts Options) Result {
+	grid.StartTime = time.Now()
+	grid.Status = STATUS_PATHING
+	grid.Tracks = nil
+
+	m := &multi{grid: grid, ctx: ctx, opts: opts}
+
+	var tracks []Track
+	var err error
+	if planner == PLANNER_CBS {
+		tracks, err = m.cbs()
+	} else {
+		tracks, err = m.prioritized()
+	}
+
+	grid.EndTime = time.Now()
+
+	outcome := OUTCOME_SUCCESS
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		outcome = OUTCOME_TIMEOUT
+	case errors.Is(err, context.Canceled):
+		outcome = OUTCOME_CANCELLED
+	case errors.Is(err, errIterationLimit):
+		outcome = OUTCOME_ITERATION_LIMIT
+	case tracks == nil:
+		outcome = OUTCOME_NOPATH
+	}
+
+	switch outcome {
+	case OUTCOME_SUCCESS:
+		grid.Status = STATUS_END_SUCCESS
+		grid.Tracks = tracks
+		grid.PathLength = sumOfCosts(tracks)
+		grid.PathCost = float64(grid.PathLength)
+	case OUTCOME_NOPATH:
+		grid.Status = STATUS_END_NOPATH
+	default:
+		grid.Status = STATUS_IDLE
+	}
+
+	return Result{
+		Outcome:    outcome,
+		PathLength: grid.PathLength,
+		PathCost:   grid.PathCost,
+		Iterations: grid.Iterations,
+		Elapsed:    grid.EndTime.Sub(grid.StartTime),
+	}
+}
+
+// sumOfCosts returns the time steps all units take to reach their ends.
+func sumOfCosts(tracks []Track) int {
+	sum := 0
+	for _, tr := range tracks {
+		sum += len(tr) - 1
+	}
+	return sum
+}
+
+// prioritized plans the units in the order of their tasks, each one avoiding the tracks of the ones before.
+// It is fast, but may fail to find tracks that exist.
+func (m *multi) prioritized() ([]Track, error) {
+	res := newReservations()
+	tracks := make([]Track, len(m.grid.Tasks))
+
+	for k, task := range m.grid.Tasks {
+		track, err := m.track(task, res)
+		if err != nil || track == nil {
+			return nil, err
+		}
+		tracks[k] = track
+
+		for t, p := range track {
+			res.add(p, t)
+			if t > 0 {
+				res.addMove(p, track[t-1], t) // No swapping cells with this unit
+			}
+		}
+		res.parked[track[len(track)-1]] = len(track) - 1
+	}
+
+	return tracks, nil
+}
+
+// cbsNode is a node of the constraint tree searched by Conflict-Based Search.
+type cbsNode struct {
+	constraints []constraint
+	tracks      []Track
+	cost        int
+}
+
+type cbsQueue []*cbsNode
+
+func (q cbsQueue) Len() int           { return len(q) }
+func (q cbsQueue) Less(i, j int) bool { return q[i].cost < q[j].cost }
+func (q cbsQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *cbsQueue) Push(x interface{}) {
+	*q = append(*q, x.(*cbsNode))
+}
+func (q *cbsQueue) Pop() interface{} {
+	old := *q
+	node := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return node
+}
+
+// cbs plans every unit on its own, then resolves the first conflict between two units by searching both
+// alternatives: one where the first unit can not take the conflicting step, and one where the second can not.
+func (m *multi) cbs() ([]Track, error) {
+	root := &cbsNode{tracks: make([]Track, len(m.grid.Tasks))}
+	for k := range m.grid.Tasks {
+		track, err := m.constrainedTrack(k, nil)
+		if err != nil || track == nil {
+			return nil, err
+		}
+		root.tracks[k] = track
+	}
+	root.cost = sumOfCosts(root.tracks)
+
+	open := cbsQueue{root}
+	for open.Len() > 0 {
+		if err := m.ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		node := heap.Pop(&open).(*cbsNode)
+		conflict := firstConflict(node.tracks)
+		if conflict == nil {
+			return node.tracks, nil
+		}
+
+		for _, c := range conflict {
+			child := &cbsNode{
+				constraints: append(append([]constraint{}, node.constraints...), c),
+				tracks:      append([]Track{}, node.tracks...),
+			}
+
+			track, err := m.constrainedTrack(c.unit, child.constraints)
+			if err != nil {
+				return nil, err
+			}
+			if track == nil {
+				continue
+			}
+
+			child.tracks[c.unit] = track
+			child.cost = sumOfCosts(child.tracks)
+			heap.Push(&open, child)
+		}
+	}
+
+	return nil, nil
+}
+
+// constrainedTrack searches the track of unit k under the constraints that apply to it.
+func (m *multi) constrainedTrack(k int, constraints []constraint) (Track, error) {
+	res := newReservations()
+	for _, c := range constraints {
+		if c.unit != k {
+			continue
+		}
+		if c.move {
+			res.addMove(c.from, c.at.p, c.at.t)
+		} else {
+			res.add(c.at.p, c.at.t)
+		}
+	}
+
+	return m.track(m.grid.Tasks[k], res)
+}
+
+// firstConflict returns the two constraints that resolve the earliest collision between two tracks, or nil if
+// there is none. Two units collide if they are on the same cell, or swap cells, in the same time step.
+func firstConflict(tracks []Track) []constraint {
+	horizon := 0
+	for _, tr := range tracks {
+		horizon = max(horizon, len(tr))
+	}
+
+	for t := 0; t < horizon; t++ {
+		for a := range tracks {
+			for b := a + 1; b < len(tracks); b++ {
+				pa, pb := tracks[a].At(t), tracks[b].At(t)
+				if pa == pb {
+					return []constraint{{unit: a, at: spaceTime{pa, t}}, {unit: b, at: spaceTime{pb, t}}}
+				}
+
+				if t > 0 && tracks[a].At(t-1) == pb && tracks[b].At(t-1) == pa {
+					return []constraint{
+						{unit: a, at: spaceTime{pa, t}, from: pb, move: true},
+						{unit: b, at: spaceTime{pb, t}, from: pa, move: true},
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// track searches the shortest track of a task that avoids res, with A* over cells and time steps.
+// It returns a nil track if there is none.
+func (m *multi) track(task Task, res *reservations) (Track, error) {
+	start, end := task.Start.Coord, task.End.Coord
+
+	// Past this time, waiting longer can not help: every reservation is over and every cell could have been reached
+	horizon := res.latest + len(m.grid.Cells)*len(m.grid.Cells[0])
+
+	root := &stNode{at: spaceTime{start, 0}, f: m.moves(start, end)}
+	open := stQueue{root}
+	closed := map[spaceTime]bool{}
+
+	for open.Len() > 0 {
+		u := heap.Pop(&open).(*stNode)
+		if closed[u.at] {
+			continue
+		}
+		closed[u.at] = true
+
+		if m.opts.MaxIterations > 0 && m.grid.Iterations >= m.opts.MaxIterations {
+			return nil, errIterationLimit
+		}
+		m.grid.Iterations++
+		if m.grid.Iterations%1024 == 0 {
+			if err := m.ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		m.grid.Cells[u.at.p.I][u.at.p.J].Visited = true
+
+		if u.at.p == end && res.canStay(end, u.at.t) {
+			var track Track
+			for node := u; node != nil; node = node.prev {
+				track = append(track, node.at.p)
+			}
+			for i, j := 0, len(track)-1; i < j; i, j = i+1, j-1 {
+				track[i], track[j] = track[j], track[i]
+			}
+			return track, nil
+		}
+
+		if u.at.t >= horizon {
+			continue
+		}
+
+		next := []pair.Pair{u.at.p} // Waiting is a move too
+		for _, n := range m.grid.Neighbors(&m.grid.Cells[u.at.p.I][u.at.p.J]) {
+			next = append(next, n.Coord)
+		}
+
+		for _, p := range next {
+			at := spaceTime{p, u.at.t + 1}
+			if closed[at] || res.blocked(u.at.p, p, at.t) {
+				continue
+			}
+
+			m.grid.Cells[p.I][p.J].Added = true
+			heap.Push(&open, &stNode{at: at, g: u.g + 1, f: u.g + 1 + m.moves(p, end), prev: u})
+		}
+	}
+
+	return nil, nil
+}
+
+// moves returns the fewest moves between a and b on an empty grid, with the grid Neighborhood.
+func (m *multi) moves(a, b pair.Pair) int {
+	di, dj := int(math.Abs(float64(a.I-b.I))), int(math.Abs(float64(a.J-b.J)))
+	if m.grid.Neighborhood == NEIGHBORHOOD_4 {
+		return di + dj
+	}
+	return max(di, dj)
+}
+
+// AddTask adds a unit going from start to end, if both are free cells that are neither walls nor flags.
+func (grid *Grid) AddTask(start, end pair.Pair) bool {
+	if start == end || grid.IsFlag(start) || grid.IsFlag(end) ||
+		grid.Cells[start.I][start.J].IsWall || grid.Cells[end.I][end.J].IsWall {
+		return false
+	}
+
+	grid.Tasks = append(grid.Tasks, Task{Start: &grid.Cells[start.I][start.J], End: &grid.Cells[end.I][end.J]})
+	return true
+}
+
+// RemoveTask removes the unit that starts or ends at p, reporting whether there was one.
+func (grid *Grid) RemoveTask(p pair.Pair) bool {
+	if k, _ := grid.TaskAt(p); k != -1 {
+		grid.Tasks = append(grid.Tasks[:k:k], grid.Tasks[k+1:]...)
+		return true
+	}
+	return false
+}
+
+// TaskAt returns the index of the task that starts or ends at p, or -1 if there is none,
+// and whether p is its start.
+func (grid *Grid) TaskAt(p pair.Pair) (int, bool) {
+	for k, task := range grid.Tasks {
+		if task.Start.Coord == p {
+			return k, true
+		} else if task.End.Coord == p {
+			return k, false
+		}
+	}
+	return -1, false
+}
@@ -0,0 +1,115 @@
+package solver
+
+import (
+	"context"
+	"math/rand"
+	"pathfinding/pair"
+	"testing"
+)
+
+// checkTracks fails t unless every track of grid goes from the start to the end of its task with moves the grid
+// Neighborhood allows, and no two units are ever on the same cell or swap cells in the same time step.
+func checkTracks(t *testing.T, grid *Grid, name string) {
+	t.Helper()
+
+	horizon := 0
+	for k, track := range grid.Tracks {
+		task := grid.Tasks[k]
+		if track[0] != task.Start.Coord || track[len(track)-1] != task.End.Coord {
+			t.Fatalf("%s: unit %d goes from %v to %v, not from %v to %v", name, k, track[0], track[len(track)-1], task.Start.Coord, task.End.Coord)
+		}
+		for s := 1; s < len(track); s++ {
+			from, to := &grid.Cells[track[s-1].I][track[s-1].J], &grid.Cells[track[s].I][track[s].J]
+			if to.IsWall || (from != to && !neighbors(grid, from, to)) {
+				t.Fatalf("%s: unit %d moves from %v to %v at time %d", name, k, from.Coord, to.Coord, s)
+			}
+		}
+		horizon = max(horizon, len(track))
+	}
+
+	for s := 0; s < horizon; s++ {
+		for a := range grid.Tracks {
+			for b := a + 1; b < len(grid.Tracks); b++ {
+				pa, pb := grid.Tracks[a].At(s), grid.Tracks[b].At(s)
+				if pa == pb {
+					t.Fatalf("%s: units %d and %d are both on %v at time %d", name, a, b, pa, s)
+				}
+				if s > 0 && grid.Tracks[a].At(s-1) == pb && grid.Tracks[b].At(s-1) == pa {
+					t.Fatalf("%s: units %d and %d swap %v and %v at time %d", name, a, b, pa, pb, s)
+				}
+			}
+		}
+	}
+}
+
+// planMulti runs planner on a clone of grid.
+func planMulti(grid *Grid, planner Planner) (Grid, Result) {
+	clone := grid.Clone()
+	return clone, PlanMulti(context.Background(), &clone, planner, Options{MaxIterations: 200000})
+}
+
+func TestPlannersAvoidCollisions(t *testing.T) {
+	rng := rand.New(rand.NewSource(6))
+
+	for _, neighborhood := range testNeighborhoods {
+		for k := 0; k < 60; k++ {
+			grid := randomGrid(rng, 4+rng.Intn(6), 4+rng.Intn(6), neighborhood, rng.Intn(25), false)
+
+			// Units start and end on distinct free cells
+			taken := map[pair.Pair]bool{}
+			free := func() pair.Pair {
+				for {
+					p := pair.New(rng.Intn(len(grid.Cells)), rng.Intn(len(grid.Cells[0])))
+					if !taken[p] && !grid.IsFlag(p) && !grid.Cells[p.I][p.J].IsWall {
+						taken[p] = true
+						return p
+					}
+				}
+			}
+			for units := 2 + rng.Intn(3); len(grid.Tasks) < units; {
+				grid.AddTask(free(), free())
+			}
+
+			prioritized, pResult := planMulti(&grid, PLANNER_PRIORITIZED)
+			cbs, cResult := planMulti(&grid, PLANNER_CBS)
+			if pResult.Outcome == OUTCOME_SUCCESS {
+				checkTracks(t, &prioritized, "prioritized")
+			}
+			if cResult.Outcome == OUTCOME_SUCCESS {
+				checkTracks(t, &cbs, "CBS")
+			}
+
+			if pResult.Outcome != OUTCOME_SUCCESS || cResult.Outcome == OUTCOME_ITERATION_LIMIT {
+				continue
+			}
+			if cResult.Outcome != OUTCOME_SUCCESS {
+				t.Fatalf("CBS on %s grid %d: outcome %s, prioritized planning found tracks", neighborhood, k, cResult.Outcome)
+			}
+			if cResult.PathLength > pResult.PathLength {
+				t.Fatalf("CBS on %s grid %d: sum of costs %d, prioritized planning got %d", neighborhood, k, cResult.PathLength, pResult.PathLength)
+			}
+		}
+	}
+}
+
+// The first unit parks on its end in a corridor the second one has to go through. Planned first, it never
+// makes way, but CBS has it wait in the side pocket until the other unit has passed.
+func TestCBSSolvesWhatPrioritizedCanNot(t *testing.T) {
+	grid := NewGrid(3, 5, pair.New(2, 0), pair.New(2, 4))
+	for j := 0; j < 5; j++ {
+		grid.Cells[1][j].IsWall = j != 2
+		grid.Cells[2][j].IsWall = j != 0 && j != 4
+	}
+	grid.AddTask(pair.New(0, 1), pair.New(0, 2))
+	grid.AddTask(pair.New(0, 0), pair.New(0, 4))
+
+	if _, result := planMulti(&grid, PLANNER_PRIORITIZED); result.Outcome != OUTCOME_NOPATH {
+		t.Fatalf("prioritized planning: outcome %s, want %s", result.Outcome, OUTCOME_NOPATH)
+	}
+
+	cbs, result := planMulti(&grid, PLANNER_CBS)
+	if result.Outcome != OUTCOME_SUCCESS {
+		t.Fatalf("CBS: outcome %s, want %s", result.Outcome, OUTCOME_SUCCESS)
+	}
+	checkTracks(t, &cbs, "CBS")
+}
@@ -0,0 +1,213 @@
+package solver
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"pathfinding/pair"
+	"strconv"
+	"strings"
+)
+
+// Maps are saved as plain text, one keyword and its values per line, and the cells last:
+//
+//	# Comments start with a '#', blank lines are skipped
+//	pathfinding map 1
+//	size <width> <height>
+//	start <row> <col>
+//	end <row> <col>
+//	waypoint <row> <col>                              (any number, in route order)
+//	unit <start row> <start col> <end row> <end col>  (any number)
+//	cells
+//	<height lines of width cell symbols>
+//
+// Rows and columns count from 0 at the top left cell. The cell symbols are '@' for walls, '.' for roads,
+// 'g' for grass, 'm' for mud, 'w' for water, and the digits 1 to 9 for any other cost of stepping into the cell.
+const MAP_HEADER = "pathfinding map 1"
+
+// Symbols of the terrain weights in map files, the other weights up to 9 being written as digits.
+var terrainSymbols = map[int]byte{WEIGHT_ROAD: '.', WEIGHT_GRASS: 'g', WEIGHT_MUD: 'm', WEIGHT_WATER: 'w'}
+
+const wallSymbol = '@'
+
+// Number of values after each keyword of map files.
+var mapKeywords = map[string]int{"size": 2, "start": 2, "end": 2, "waypoint": 2, "unit": 4, "cells": 0}
+
+// WriteMap writes the layout and flags of grid to w in the map file format.
+func WriteMap(w io.Writer, grid *Grid) error {
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintln(out, MAP_HEADER)
+	fmt.Fprintf(out, "size %d %d\n", len(grid.Cells[0]), len(grid.Cells))
+	fmt.Fprintf(out, "start %d %d\n", grid.Start.Coord.I, grid.Start.Coord.J)
+	fmt.Fprintf(out, "end %d %d\n", grid.End.Coord.I, grid.End.Coord.J)
+	for _, waypoint := range grid.Waypoints {
+		fmt.Fprintf(out, "waypoint %d %d\n", waypoint.Coord.I, waypoint.Coord.J)
+	}
+	for _, task := range grid.Tasks {
+		fmt.Fprintf(out, "unit %d %d %d %d\n", task.Start.Coord.I, task.Start.Coord.J, task.End.Coord.I, task.End.Coord.J)
+	}
+
+	fmt.Fprintln(out, "cells")
+	for _, row := range grid.Cells {
+		line := make([]byte, len(row))
+		for j, node := range row {
+			symbol, ok := terrainSymbols[node.Weight]
+			if node.IsWall {
+				symbol = wallSymbol
+			} else if !ok && node.Weight >= 1 && node.Weight <= 9 {
+				symbol = byte('0' + node.Weight)
+			} else if !ok {
+				return fmt.Errorf("cell %v: weight %d has no symbol", node.Coord, node.Weight)
+			}
+			line[j] = symbol
+		}
+		out.Write(line)
+		out.WriteByte('\n')
+	}
+
+	return out.Flush()
+}
+
+// ReadMap reads a grid from r in the map file format.
+func ReadMap(r io.Reader) (Grid, error) {
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	next := func() (string, bool) {
+		for scanner.Scan() {
+			lineNumber++
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				return line, true
+			}
+		}
+		return "", false
+	}
+
+	if line, _ := next(); line != MAP_HEADER {
+		return Grid{}, fmt.Errorf("line %d: expected %q", lineNumber, MAP_HEADER)
+	}
+
+	var grid Grid
+	var start, end *pair.Pair
+	var waypoints []pair.Pair
+	var units [][2]pair.Pair
+	for {
+		line, ok := next()
+		if !ok {
+			return Grid{}, fmt.Errorf("line %d: missing cells", lineNumber)
+		}
+		fields := strings.Fields(line)
+
+		values := make([]int, len(fields)-1)
+		for k, field := range fields[1:] {
+			value, err := strconv.Atoi(field)
+			if err != nil {
+				return Grid{}, fmt.Errorf("line %d: %q is not a number", lineNumber, field)
+			}
+			values[k] = value
+		}
+
+		if want, known := mapKeywords[fields[0]]; !known {
+			return Grid{}, fmt.Errorf("line %d: unknown keyword %q", lineNumber, fields[0])
+		} else if len(values) != want {
+			return Grid{}, fmt.Errorf("line %d: %s takes %d values", lineNumber, fields[0], want)
+		}
+
+		switch fields[0] {
+		case "size":
+			if values[0] < 1 || values[1] < 1 {
+				return Grid{}, fmt.Errorf("line %d: size must be positive", lineNumber)
+			}
+			grid = NewGrid(values[1], values[0], pair.New(0, 0), pair.New(0, 0))
+		case "start":
+			p := pair.New(values[0], values[1])
+			start = &p
+		case "end":
+			p := pair.New(values[0], values[1])
+			end = &p
+		case "waypoint":
+			waypoints = append(waypoints, pair.New(values[0], values[1]))
+		case "unit":
+			units = append(units, [2]pair.Pair{pair.New(values[0], values[1]), pair.New(values[2], values[3])})
+		}
+
+		if fields[0] == "cells" {
+			break
+		}
+	}
+
+	if grid.Cells == nil {
+		return Grid{}, fmt.Errorf("line %d: cells before size", lineNumber)
+	}
+	rows, cols := len(grid.Cells), len(grid.Cells[0])
+
+	for i := 0; i < rows; i++ {
+		line, ok := next()
+		if !ok {
+			return Grid{}, fmt.Errorf("line %d: expected %d rows of cells, got %d", lineNumber, rows, i)
+		} else if len(line) != cols {
+			return Grid{}, fmt.Errorf("line %d: expected %d cells, got %d", lineNumber, cols, len(line))
+		}
+
+		for j := 0; j < cols; j++ {
+			node := &grid.Cells[i][j]
+			switch symbol := line[j]; {
+			case symbol == wallSymbol:
+				node.IsWall = true
+			case symbol >= '1' && symbol <= '9':
+				node.Weight = int(symbol - '0')
+			default:
+				found := false
+				for weight, terrain := range terrainSymbols {
+					if symbol == terrain {
+						node.Weight, found = weight, true
+					}
+				}
+				if !found {
+					return Grid{}, fmt.Errorf("line %d: unknown cell symbol %q", lineNumber, symbol)
+				}
+			}
+		}
+	}
+	if line, ok := next(); ok {
+		return Grid{}, fmt.Errorf("line %d: unexpected %q after the cells", lineNumber, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return Grid{}, err
+	}
+
+	// Flags go last, as they must be on open cells
+	open := func(p *pair.Pair, name string) (*Node, error) {
+		if p == nil {
+			return nil, fmt.Errorf("missing %s", name)
+		} else if !p.InBounds(0, 0, rows, cols) || grid.Cells[p.I][p.J].IsWall {
+			return nil, fmt.Errorf("%s %v is not an open cell", name, *p)
+		}
+		return &grid.Cells[p.I][p.J], nil
+	}
+
+	var err error
+	if grid.Start, err = open(start, "start"); err != nil {
+		return Grid{}, err
+	}
+	if grid.End, err = open(end, "end"); err != nil {
+		return Grid{}, err
+	}
+	if grid.Start == grid.End {
+		return Grid{}, fmt.Errorf("start and end are both at %v", *start)
+	}
+
+	for _, p := range waypoints {
+		if !p.InBounds(0, 0, rows, cols) || !grid.ToggleWaypoint(p) {
+			return Grid{}, fmt.Errorf("waypoint %v is not a free open cell", p)
+		}
+	}
+	for _, unit := range units {
+		if !unit[0].InBounds(0, 0, rows, cols) || !unit[1].InBounds(0, 0, rows, cols) || !grid.AddTask(unit[0], unit[1]) {
+			return Grid{}, fmt.Errorf("unit %v to %v is not between free open cells", unit[0], unit[1])
+		}
+	}
+
+	return grid, nil
+}
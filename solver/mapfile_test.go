@@ -0,0 +1,86 @@
+package solver
+
+import (
+	"bytes"
+	"math/rand"
+	"pathfinding/pair"
+	"strings"
+	"testing"
+)
+
+func TestMapRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+
+	for k := 0; k < 50; k++ {
+		grid := randomGrid(rng, 1+rng.Intn(20), 2+rng.Intn(20), NEIGHBORHOOD_4, rng.Intn(40), true)
+		for tries := 0; tries < 5; tries++ {
+			p := pair.New(rng.Intn(len(grid.Cells)), rng.Intn(len(grid.Cells[0])))
+			switch node := &grid.Cells[p.I][p.J]; tries {
+			case 0:
+				node.Weight = 1 + rng.Intn(9) // Weights without a terrain are written as digits
+			case 1, 2:
+				grid.ToggleWaypoint(p)
+			default:
+				grid.AddTask(p, pair.New(rng.Intn(len(grid.Cells)), rng.Intn(len(grid.Cells[0]))))
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := WriteMap(&buf, &grid); err != nil {
+			t.Fatalf("grid %d: %v", k, err)
+		}
+		read, err := ReadMap(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("grid %d: %v\n%s", k, err, buf.String())
+		}
+
+		if len(read.Cells) != len(grid.Cells) || len(read.Cells[0]) != len(grid.Cells[0]) {
+			t.Fatalf("grid %d: read %dx%d, wrote %dx%d", k, len(read.Cells), len(read.Cells[0]), len(grid.Cells), len(grid.Cells[0]))
+		}
+		for i := range grid.Cells {
+			for j, node := range grid.Cells[i] {
+				if got := read.Cells[i][j]; got.IsWall != node.IsWall || (!node.IsWall && got.Weight != node.Weight) {
+					t.Fatalf("grid %d: cell %v read as wall %t weight %d, wrote wall %t weight %d", k, node.Coord, got.IsWall, got.Weight, node.IsWall, node.Weight)
+				}
+			}
+		}
+
+		if read.Start.Coord != grid.Start.Coord || read.End.Coord != grid.End.Coord {
+			t.Fatalf("grid %d: flags read at %v and %v, wrote %v and %v", k, read.Start.Coord, read.End.Coord, grid.Start.Coord, grid.End.Coord)
+		}
+		if len(read.Waypoints) != len(grid.Waypoints) || len(read.Tasks) != len(grid.Tasks) {
+			t.Fatalf("grid %d: read %d waypoints and %d units, wrote %d and %d", k, len(read.Waypoints), len(read.Tasks), len(grid.Waypoints), len(grid.Tasks))
+		}
+		for w, waypoint := range grid.Waypoints {
+			if read.Waypoints[w].Coord != waypoint.Coord {
+				t.Fatalf("grid %d: waypoint %d read at %v, wrote %v", k, w, read.Waypoints[w].Coord, waypoint.Coord)
+			}
+		}
+		for u, task := range grid.Tasks {
+			if read.Tasks[u].Start.Coord != task.Start.Coord || read.Tasks[u].End.Coord != task.End.Coord {
+				t.Fatalf("grid %d: unit %d read from %v to %v, wrote %v to %v", k, u, read.Tasks[u].Start.Coord, read.Tasks[u].End.Coord, task.Start.Coord, task.End.Coord)
+			}
+		}
+	}
+}
+
+func TestReadMapErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"header", "pathfinding map 2\nsize 2 1\nstart 0 0\nend 0 1\ncells\n..\n"},
+		{"keyword", "pathfinding map 1\nsize 2 1\nflag 0 0\ncells\n..\n"},
+		{"values", "pathfinding map 1\nsize 2\nstart 0 0\nend 0 1\ncells\n..\n"},
+		{"short row", "pathfinding map 1\nsize 2 1\nstart 0 0\nend 0 1\ncells\n.\n"},
+		{"symbol", "pathfinding map 1\nsize 2 1\nstart 0 0\nend 0 1\ncells\n.x\n"},
+		{"start on a wall", "pathfinding map 1\nsize 2 1\nstart 0 0\nend 0 1\ncells\n@.\n"},
+		{"missing end", "pathfinding map 1\nsize 2 1\nstart 0 0\ncells\n..\n"},
+	}
+
+	for _, test := range tests {
+		if _, err := ReadMap(strings.NewReader(test.text)); err == nil {
+			t.Errorf("%s: read without error", test.name)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package solver
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"pathfinding/pair"
+	"strconv"
+	"strings"
+)
+
+// Moving AI benchmark maps (https://movingai.com/benchmarks/formats.html) start with a header,
+//
+//	type octile
+//	height <rows>
+//	width <cols>
+//	map
+//
+// followed by a line of tiles per row. '.' and 'G' are ground, 'S' is swamp, which is passable too,
+// and '@', 'O', 'T' (trees) and 'W' (water) are walls. Benchmarks move 8-way without cutting corners,
+// so grids read from them use NEIGHBORHOOD_8_NO_CORNERS and HEURISTIC_OCTILE.
+
+// Tiles of Moving AI maps that can be walked on.
+const movingAIOpenTiles = ".GS"
+
+// Tiles of Moving AI maps that are walls.
+const movingAIWallTiles = "@OTW"
+
+// ReadMovingAIMap reads a grid from r in the Moving AI map format, with the flags on the open cells nearest
+// to its bottom left and top right corners. Scenarios move them before searching.
+func ReadMovingAIMap(r io.Reader) (Grid, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	lineNumber := 0
+
+	rows, cols := -1, -1
+	for scanner.Scan() {
+		lineNumber++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		} else if fields[0] == "map" {
+			break
+		} else if len(fields) != 2 {
+			return Grid{}, fmt.Errorf("line %d: expected a header line", lineNumber)
+		}
+
+		switch fields[0] {
+		case "type":
+			if fields[1] != "octile" {
+				return Grid{}, fmt.Errorf("line %d: unsupported map type %q", lineNumber, fields[1])
+			}
+		case "height", "width":
+			value, err := strconv.Atoi(fields[1])
+			if err != nil || value < 1 {
+				return Grid{}, fmt.Errorf("line %d: %s must be a positive number", lineNumber, fields[0])
+			}
+			if fields[0] == "height" {
+				rows = value
+			} else {
+				cols = value
+			}
+		default:
+			return Grid{}, fmt.Errorf("line %d: unknown header %q", lineNumber, fields[0])
+		}
+	}
+	if rows == -1 || cols == -1 {
+		return Grid{}, fmt.Errorf("line %d: missing height or width", lineNumber)
+	}
+
+	grid := NewGrid(rows, cols, pair.New(rows-1, 0), pair.New(0, cols-1))
+	grid.Neighborhood = NEIGHBORHOOD_8_NO_CORNERS
+	grid.Heuristic = HEURISTIC_OCTILE
+
+	for i := 0; i < rows; i++ {
+		if !scanner.Scan() {
+			return Grid{}, fmt.Errorf("line %d: expected %d rows of tiles, got %d", lineNumber, rows, i)
+		}
+		lineNumber++
+
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) != cols {
+			return Grid{}, fmt.Errorf("line %d: expected %d tiles, got %d", lineNumber, cols, len(line))
+		}
+		for j := 0; j < cols; j++ {
+			if strings.IndexByte(movingAIWallTiles, line[j]) != -1 {
+				grid.Cells[i][j].IsWall = true
+			} else if strings.IndexByte(movingAIOpenTiles, line[j]) == -1 {
+				return Grid{}, fmt.Errorf("line %d: unknown tile %q", lineNumber, line[j])
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return Grid{}, err
+	}
+
+	grid.Start = grid.nearestOpen(pair.New(rows-1, 0), nil)
+	grid.End = grid.nearestOpen(pair.New(0, cols-1), grid.Start)
+	if grid.Start == nil || grid.End == nil {
+		return Grid{}, fmt.Errorf("map has less than two open cells")
+	}
+	return grid, nil
+}
+
+// nearestOpen returns the open cell other than except nearest to p, or nil if there is none.
+func (grid *Grid) nearestOpen(p pair.Pair, except *Node) *Node {
+	var nearest *Node
+	for i := range grid.Cells {
+		for j := range grid.Cells[i] {
+			node := &grid.Cells[i][j]
+			if !node.IsWall && node != except && (nearest == nil || p.Dist(node.Coord) < p.Dist(nearest.Coord)) {
+				nearest = node
+			}
+		}
+	}
+	return nearest
+}
+
+// WriteMovingAIMap writes the walls of grid to w in the Moving AI map format. The format has no flags
+// nor terrain costs, so every open cell is written as ground.
+func WriteMovingAIMap(w io.Writer, grid *Grid) error {
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, "type octile\nheight %d\nwidth %d\nmap\n", len(grid.Cells), len(grid.Cells[0]))
+	for _, row := range grid.Cells {
+		for _, node := range row {
+			if node.IsWall {
+				out.WriteByte('@')
+			} else {
+				out.WriteByte('.')
+			}
+		}
+		out.WriteByte('\n')
+	}
+
+	return out.Flush()
+}
+
+// A Scenario is a search of a Moving AI scenario file, with the cost of its shortest path.
+type Scenario struct {
+	Bucket        int
+	Map           string // Path of the map, as written in the scenario file
+	Width, Height int
+	Start, End    pair.Pair
+	Optimal       float64
+}
+
+// ReadScenarios reads the scenarios of a Moving AI scenario file, converting their x, y positions to cells.
+func ReadScenarios(r io.Reader) ([]Scenario, error) {
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+
+	var scenarios []Scenario
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || lineNumber == 1 && strings.HasPrefix(line, "version") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 9 {
+			return nil, fmt.Errorf("line %d: expected 9 fields, got %d", lineNumber, len(fields))
+		}
+
+		var values [7]int
+		for k, field := range append(fields[:1:1], fields[2:8]...) {
+			value, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %q is not a number", lineNumber, field)
+			}
+			values[k] = value
+		}
+		optimal, err := strconv.ParseFloat(fields[8], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %q is not a number", lineNumber, fields[8])
+		}
+
+		scenarios = append(scenarios, Scenario{
+			Bucket: values[0],
+			Map:    fields[1],
+			Width:  values[1], Height: values[2],
+			Start:   pair.New(values[4], values[3]),
+			End:     pair.New(values[6], values[5]),
+			Optimal: optimal,
+		})
+	}
+
+	return scenarios, scanner.Err()
+}
+
+// A ScenarioResult is the outcome of searching a Scenario, and whether its path cost is the optimal one.
+type ScenarioResult struct {
+	Scenario Scenario
+	Result   Result
+	Optimal  bool
+}
+
+// RunScenario searches a clone of grid between the flags of sc with s, and checks the cost of the path found
+// against the optimal one. Moving AI lengths are costs, diagonals counting sqrt(2), so they are compared
+// with PathCost; PathLength counts cells. The optimal costs are written with 8 decimals, hence the tolerance.
+func RunScenario(ctx context.Context, grid *Grid, sc Scenario, s Solver, opts Options) (ScenarioResult, error) {
+	rows, cols := len(grid.Cells), len(grid.Cells[0])
+	if sc.Width != cols || sc.Height != rows {
+		return ScenarioResult{}, fmt.Errorf("scenario is for a %dx%d map, not %dx%d", sc.Width, sc.Height, cols, rows)
+	}
+	for _, p := range []pair.Pair{sc.Start, sc.End} {
+		if !grid.walkable(p) {
+			return ScenarioResult{}, fmt.Errorf("scenario flag %v is not an open cell", p)
+		}
+	}
+
+	clone := grid.Clone()
+	clone.Start, clone.End = &clone.Cells[sc.Start.I][sc.Start.J], &clone.Cells[sc.End.I][sc.End.J]
+	clone.Waypoints, clone.Tasks = nil, nil
+
+	result := Solve(ctx, &clone, s, opts)
+	optimal := result.Outcome == OUTCOME_SUCCESS && math.Abs(result.PathCost-sc.Optimal) <= 1e-4*math.Max(1, sc.Optimal)
+	return ScenarioResult{Scenario: sc, Result: result, Optimal: optimal}, nil
+}
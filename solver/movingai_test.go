@@ -0,0 +1,99 @@
+package solver
+
+import (
+	"context"
+	"pathfinding/pair"
+	"strings"
+	"testing"
+)
+
+const testMovingAIMap = `type octile
+height 4
+width 5
+map
+.....
+.@@T.
+.G.S.
+.....
+`
+
+const testScenarios = `version 1
+0	test.map	5	4	0	0	4	3	6.41421356
+0	test.map	5	4	2	2	4	0	4.00000000
+`
+
+func TestReadMovingAI(t *testing.T) {
+	grid, err := ReadMovingAIMap(strings.NewReader(testMovingAIMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(grid.Cells) != 4 || len(grid.Cells[0]) != 5 {
+		t.Fatalf("read %dx%d, want 4x5", len(grid.Cells), len(grid.Cells[0]))
+	}
+	for i, row := range strings.Split(testMovingAIMap, "\n")[4:8] {
+		for j := range row {
+			if wall := strings.IndexByte(movingAIWallTiles, row[j]) != -1; grid.Cells[i][j].IsWall != wall {
+				t.Fatalf("tile %q at %v read as wall %t", row[j], pair.New(i, j), grid.Cells[i][j].IsWall)
+			}
+		}
+	}
+	if grid.Neighborhood != NEIGHBORHOOD_8_NO_CORNERS {
+		t.Fatalf("neighborhood %s, want %s", grid.Neighborhood, NEIGHBORHOOD_8_NO_CORNERS)
+	}
+
+	scenarios, err := ReadScenarios(strings.NewReader(testScenarios))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scenarios) != 2 {
+		t.Fatalf("read %d scenarios, want 2", len(scenarios))
+	}
+	if sc := scenarios[0]; sc.Map != "test.map" || sc.Start != pair.New(0, 0) || sc.End != pair.New(3, 4) {
+		t.Fatalf("scenario read as %+v", sc)
+	}
+
+	// Going around the walls without cutting their corners
+	for k, sc := range scenarios {
+		result, err := RunScenario(context.Background(), &grid, sc, New("A*"), Options{})
+		if err != nil {
+			t.Fatalf("scenario %d: %v", k, err)
+		}
+		if !result.Optimal {
+			t.Fatalf("scenario %d: path cost %f, want %f", k, result.Result.PathCost, sc.Optimal)
+		}
+	}
+
+	sc := scenarios[0]
+	sc.Width = 6
+	if _, err := RunScenario(context.Background(), &grid, sc, New("A*"), Options{}); err == nil {
+		t.Fatalf("ran a scenario for a 6x4 map on a 5x4 one")
+	}
+}
+
+func TestReadMovingAIErrors(t *testing.T) {
+	maps := []struct {
+		name string
+		text string
+	}{
+		{"type", "type square\nheight 1\nwidth 2\nmap\n..\n"},
+		{"height", "type octile\nheight x\nwidth 2\nmap\n..\n"},
+		{"missing width", "type octile\nheight 1\nmap\n..\n"},
+		{"header", "type octile\nheight 1\nwidth 2\ndepth 1\nmap\n..\n"},
+		{"header values", "type octile\nheight 1 2\nwidth 2\nmap\n..\n"},
+		{"short row", "type octile\nheight 1\nwidth 2\nmap\n.\n"},
+		{"missing row", "type octile\nheight 2\nwidth 2\nmap\n..\n"},
+		{"tile", "type octile\nheight 1\nwidth 2\nmap\n.x\n"},
+		{"one open cell", "type octile\nheight 1\nwidth 2\nmap\n.@\n"},
+	}
+	for _, test := range maps {
+		if _, err := ReadMovingAIMap(strings.NewReader(test.text)); err == nil {
+			t.Errorf("%s: read without error", test.name)
+		}
+	}
+
+	for _, text := range []string{"version 1\n0\ttest.map\t5\t4\t0\t0\t4\t3\n", "0\ttest.map\t5\t4\t0\t0\t4\tx\t1.0\n"} {
+		if _, err := ReadScenarios(strings.NewReader(text)); err == nil {
+			t.Errorf("read scenarios %q without error", text)
+		}
+	}
+}
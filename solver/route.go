@@ -0,0 +1,318 @@
+package solver
+
+import (
+	"context"
+	"math"
+	"pathfinding/pair"
+)
+
+// RouteMode is how a route goes through the waypoints of a grid.
+type RouteMode string
+
+const (
+	ROUTE_NEAREST    RouteMode = "ROUTE_NEAREST"    // Reach whichever of End and the waypoints is the cheapest
+	ROUTE_IN_ORDER   RouteMode = "ROUTE_IN_ORDER"   // Visit the waypoints in the order they were placed, then End
+	ROUTE_BEST_ORDER RouteMode = "ROUTE_BEST_ORDER" // Visit the waypoints in the cheapest order, then End
+)
+
+// A leg is the shortest path between two stops of a route, searched on its own copy of the grid.
+type leg struct {
+	from, to pair.Pair
+	ok       bool
+	cost     float64
+	length   int         // Cells between from and to
+	cells    []pair.Pair // Cells of the path, from and to included
+}
+
+// A router is a Solver that searches a route through the waypoints of a grid, one leg at a time,
+// with a solver made by newSolver for each leg. Its iterations are the ones of all its legs.
+type router struct {
+	name      string
+	mode      RouteMode
+	newSolver func() Solver
+
+	grid    *Grid
+	legs    []*leg
+	current int
+
+	legGrid   Grid
+	legSearch *Search
+}
+
+// NewRouter returns a Solver that searches a route from Start to End through the grid Waypoints according to mode,
+// solving every leg with a solver returned by newSolver. In ROUTE_BEST_ORDER, every pair of stops is searched.
+func NewRouter(mode RouteMode, newSolver func() Solver) Solver {
+	return &router{name: newSolver().Name(), mode: mode, newSolver: newSolver}
+}
+
+func (r *router) Name() string {
+	return r.name
+}
+
+func (r *router) Init(grid *Grid) {
+	r.grid = grid
+	r.current = 0
+	r.legSearch = nil
+	grid.Visits = nil
+
+	start, end := grid.Start.Coord, grid.End.Coord
+	waypoints := make([]pair.Pair, len(grid.Waypoints))
+	for k, w := range grid.Waypoints {
+		waypoints[k] = w.Coord
+	}
+
+	r.legs = nil
+	switch r.mode {
+	case ROUTE_NEAREST:
+		for _, goal := range append([]pair.Pair{end}, waypoints...) {
+			r.legs = append(r.legs, &leg{from: start, to: goal})
+		}
+	case ROUTE_IN_ORDER:
+		stops := append(append([]pair.Pair{start}, waypoints...), end)
+		for k := 1; k < len(stops); k++ {
+			r.legs = append(r.legs, &leg{from: stops[k-1], to: stops[k]})
+		}
+	case ROUTE_BEST_ORDER:
+		if len(waypoints) == 0 {
+			r.legs = append(r.legs, &leg{from: start, to: end})
+		}
+		for _, a := range waypoints {
+			r.legs = append(r.legs, &leg{from: start, to: a}, &leg{from: a, to: end})
+			for _, b := range waypoints {
+				if a != b {
+					r.legs = append(r.legs, &leg{from: a, to: b})
+				}
+			}
+		}
+	}
+}
+
+// Step runs one iteration of the current leg, marking what it touches on the routed grid.
+func (r *router) Step() StepResult {
+	if r.current >= len(r.legs) {
+		return StepResult{Done: true}
+	}
+
+	l := r.legs[r.current]
+	if r.legSearch == nil {
+		r.legGrid = r.grid.Clone()
+		r.legGrid.Start = &r.legGrid.Cells[l.from.I][l.from.J]
+		r.legGrid.End = &r.legGrid.Cells[l.to.I][l.to.J]
+		r.legSearch = NewSearch(context.Background(), &r.legGrid, r.newSolver(), Options{})
+	}
+
+	res := r.legSearch.Step()
+
+	step := StepResult{}
+	if res.Popped != nil {
+		step.Popped = &r.grid.Cells[res.Popped.Coord.I][res.Popped.Coord.J]
+		step.Popped.Visited = true
+	}
+	for _, n := range res.Pushed {
+		pushed := &r.grid.Cells[n.Coord.I][n.Coord.J]
+		pushed.Added = true
+		step.Pushed = append(step.Pushed, pushed)
+	}
+
+	if res.Done {
+		l.ok = r.legGrid.Status == STATUS_END_SUCCESS
+		l.cost = r.legGrid.PathCost
+		l.length = r.legGrid.PathLength
+		for i := range r.legGrid.Cells {
+			for j := range r.legGrid.Cells[i] {
+				if r.legGrid.Cells[i][j].IsPath {
+					l.cells = append(l.cells, pair.New(i, j))
+				}
+			}
+		}
+
+		r.legSearch = nil
+		r.current++
+
+		// A missing leg leaves no way to visit every stop in order
+		if !l.ok && r.mode == ROUTE_IN_ORDER {
+			r.current = len(r.legs)
+		}
+	}
+
+	step.Done = r.current >= len(r.legs)
+	return step
+}
+
+// Result marks the legs of the route found and sets its length and cost, and the order of its stops in Visits.
+func (r *router) Result() Status {
+	visits := r.order()
+	if visits == nil {
+		return STATUS_END_NOPATH
+	}
+
+	for k := 1; k < len(visits); k++ {
+		l := r.leg(visits[k-1], visits[k])
+		for _, p := range l.cells {
+			r.grid.Cells[p.I][p.J].IsPath = true
+		}
+
+		r.grid.PathCost += l.cost
+		r.grid.PathLength += l.length
+		if k < len(visits)-1 {
+			r.grid.PathLength++ // The stop between this leg and the next
+		}
+	}
+
+	for _, p := range visits {
+		r.grid.Visits = append(r.grid.Visits, &r.grid.Cells[p.I][p.J])
+	}
+
+	return STATUS_END_SUCCESS
+}
+
+// order returns the stops of the cheapest route allowed by the mode, Start first, or nil if there is none.
+func (r *router) order() []pair.Pair {
+	start := r.grid.Start.Coord
+
+	switch r.mode {
+	case ROUTE_NEAREST:
+		var nearest *leg
+		for _, l := range r.legs {
+			if l.ok && (nearest == nil || l.cost < nearest.cost) {
+				nearest = l
+			}
+		}
+		if nearest == nil {
+			return nil
+		}
+		return []pair.Pair{start, nearest.to}
+
+	case ROUTE_IN_ORDER:
+		visits := []pair.Pair{start}
+		for _, l := range r.legs {
+			if !l.ok {
+				return nil
+			}
+			visits = append(visits, l.to)
+		}
+		return visits
+
+	default:
+		return r.bestOrder()
+	}
+}
+
+// bestOrder solves the travelling salesman problem over the waypoints with the costs of the legs between them,
+// from Start to End, using the Held-Karp dynamic programming algorithm.
+func (r *router) bestOrder() []pair.Pair {
+	start, end := r.grid.Start.Coord, r.grid.End.Coord
+	if len(r.grid.Waypoints) == 0 {
+		if l := r.leg(start, end); l.ok {
+			return []pair.Pair{start, end}
+		}
+		return nil
+	}
+
+	waypoints := make([]pair.Pair, len(r.grid.Waypoints))
+	for k, w := range r.grid.Waypoints {
+		waypoints[k] = w.Coord
+	}
+
+	cost := func(a, b pair.Pair) float64 {
+		if l := r.leg(a, b); l.ok {
+			return l.cost
+		}
+		return math.Inf(1)
+	}
+
+	// best[set][k] is the cheapest cost from Start through the waypoints in set, ending at waypoint k
+	n := len(waypoints)
+	best := make([][]float64, 1<<n)
+	prev := make([][]int, 1<<n)
+	for set := range best {
+		best[set] = make([]float64, n)
+		prev[set] = make([]int, n)
+		for k := range best[set] {
+			best[set][k] = math.Inf(1)
+			prev[set][k] = -1
+		}
+	}
+	for k := range waypoints {
+		best[1<<k][k] = cost(start, waypoints[k])
+	}
+
+	for set := 1; set < 1<<n; set++ {
+		for k := 0; k < n; k++ {
+			if set&(1<<k) == 0 || math.IsInf(best[set][k], 1) {
+				continue
+			}
+			for next := 0; next < n; next++ {
+				if set&(1<<next) != 0 {
+					continue
+				}
+				alt := best[set][k] + cost(waypoints[k], waypoints[next])
+				if alt < best[set|1<<next][next] {
+					best[set|1<<next][next] = alt
+					prev[set|1<<next][next] = k
+				}
+			}
+		}
+	}
+
+	all := 1<<n - 1
+	last, total := -1, math.Inf(1)
+	for k := range waypoints {
+		if alt := best[all][k] + cost(waypoints[k], end); alt < total {
+			last, total = k, alt
+		}
+	}
+	if last == -1 {
+		return nil
+	}
+
+	visits := []pair.Pair{end}
+	for set, k := all, last; k != -1; set, k = set&^(1<<k), prev[set][k] {
+		visits = append(visits, waypoints[k])
+	}
+	visits = append(visits, start)
+
+	for i, j := 0, len(visits)-1; i < j; i, j = i+1, j-1 {
+		visits[i], visits[j] = visits[j], visits[i]
+	}
+	return visits
+}
+
+// leg returns the searched leg from a to b, or an unreachable one if it was never searched.
+func (r *router) leg(a, b pair.Pair) *leg {
+	for _, l := range r.legs {
+		if l.from == a && l.to == b {
+			return l
+		}
+	}
+	return &leg{from: a, to: b}
+}
+
+// ToggleWaypoint adds a waypoint on the cell at p, or removes it if there was one already.
+// Walls, Start and End can not be waypoints; it reports whether the cell is a waypoint afterwards.
+func (grid *Grid) ToggleWaypoint(p pair.Pair) bool {
+	for k, w := range grid.Waypoints {
+		if w.Coord == p {
+			grid.Waypoints = append(grid.Waypoints[:k:k], grid.Waypoints[k+1:]...)
+			return false
+		}
+	}
+
+	node := &grid.Cells[p.I][p.J]
+	if node.IsWall || node == grid.Start || node == grid.End {
+		return false
+	}
+
+	grid.Waypoints = append(grid.Waypoints, node)
+	return true
+}
+
+// IsWaypoint reports whether the cell at p is a waypoint.
+func (grid *Grid) IsWaypoint(p pair.Pair) bool {
+	for _, w := range grid.Waypoints {
+		if w.Coord == p {
+			return true
+		}
+	}
+	return false
+}
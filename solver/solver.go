@@ -0,0 +1,232 @@
+// Package solver implements grid pathfinding algorithms with no dependency on the GUI.
+package solver
+
+import (
+	"context"
+	"errors"
+	"pathfinding/pair"
+	"time"
+)
+
+// Solver is a pathfinding algorithm that searches a Grid one iteration at a time.
+type Solver interface {
+	// Name returns the display name of the algorithm.
+	Name() string
+	// Init prepares the solver to search grid from grid.Start to grid.End.
+	Init(grid *Grid)
+	// Step runs a single iteration of the search and reports what it changed.
+	Step() StepResult
+	// Result builds the path found, if any, and returns the final Status.
+	Result() Status
+}
+
+// An IncrementalSolver keeps its search state once done, so it can repair the path when cells change
+// instead of searching again from scratch.
+type IncrementalSolver interface {
+	Solver
+	// CellChanged updates the search after the wall or weight of n changed.
+	CellChanged(n *Node)
+}
+
+// A StepResult describes the nodes touched by a single Step.
+type StepResult struct {
+	Popped *Node   // Node taken out of the open set, nil if there was none
+	Pushed []*Node // Neighbours added to the open set or whose cost was lowered
+	Done   bool    // Whether the search has finished
+}
+
+var (
+	solverFactories = map[string]func() Solver{}
+	solverNames     []string
+)
+
+// Register makes a solver available under name. It is meant to be called from init functions.
+func Register(name string, factory func() Solver) {
+	if _, exists := solverFactories[name]; exists {
+		panic("solver already registered: " + name)
+	}
+	solverFactories[name] = factory
+	solverNames = append(solverNames, name)
+}
+
+// New returns a new instance of the solver registered as name, or nil if there is none.
+func New(name string) Solver {
+	factory, ok := solverFactories[name]
+	if !ok {
+		return nil
+	}
+	return factory()
+}
+
+// Names returns the names of the registered solvers, in registration order.
+func Names() []string {
+	return solverNames
+}
+
+// An Outcome is the reason a search finished.
+type Outcome string
+
+const (
+	OUTCOME_SUCCESS         Outcome = "OUTCOME_SUCCESS"
+	OUTCOME_NOPATH          Outcome = "OUTCOME_NOPATH"
+	OUTCOME_CANCELLED       Outcome = "OUTCOME_CANCELLED"
+	OUTCOME_TIMEOUT         Outcome = "OUTCOME_TIMEOUT"
+	OUTCOME_ITERATION_LIMIT Outcome = "OUTCOME_ITERATION_LIMIT"
+)
+
+// Options limit a search beyond what its context allows.
+type Options struct {
+	MaxIterations int // 0 means no limit
+}
+
+// A Result summarizes a finished search.
+type Result struct {
+	Outcome    Outcome
+	PathLength int
+	PathCost   float64
+	Iterations int
+	Elapsed    time.Duration
+}
+
+// A Search drives a Solver over a Grid one step at a time, keeping the grid's status and stats up to date.
+// Step mutates the grid, so anything reading it (e.g. rendering) must run on the goroutine calling Step.
+type Search struct {
+	Grid   *Grid
+	Solver Solver
+
+	ctx     context.Context
+	opts    Options
+	outcome Outcome
+
+	// Flags and settings the solver was initialized with, which a replan can not repair a change of
+	start, end      pair.Pair
+	neighborhood    Neighborhood
+	heuristic       Heuristic
+	heuristicWeight float64
+}
+
+// NewSearch initializes s on grid and marks the grid as pathing.
+// The search stops early once ctx is done or opts.MaxIterations is reached.
+func NewSearch(ctx context.Context, grid *Grid, s Solver, opts Options) *Search {
+	grid.StartTime = time.Now()
+	grid.Status = STATUS_PATHING
+
+	s.Init(grid)
+
+	search := &Search{Grid: grid, Solver: s, ctx: ctx, opts: opts}
+	search.keepSettings()
+	return search
+}
+
+// keepSettings remembers the flags and settings of the grid the solver is initialized with.
+func (s *Search) keepSettings() {
+	s.start, s.end = s.Grid.Start.Coord, s.Grid.End.Coord
+	s.neighborhood, s.heuristic, s.heuristicWeight = s.Grid.Neighborhood, s.Grid.Heuristic, s.Grid.HeuristicWeight
+}
+
+// settingsChanged reports whether the flags or settings of the grid changed since the solver was initialized.
+func (s *Search) settingsChanged() bool {
+	return s.start != s.Grid.Start.Coord || s.end != s.Grid.End.Coord || s.neighborhood != s.Grid.Neighborhood ||
+		s.heuristic != s.Grid.Heuristic || s.heuristicWeight != s.Grid.HeuristicWeight
+}
+
+// Step runs a single iteration of the search. Once the solver is done, the path is built and the grid status is set.
+func (s *Search) Step() StepResult {
+	if s.Done() {
+		return StepResult{Done: true}
+	}
+
+	if err := s.ctx.Err(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			s.stop(OUTCOME_TIMEOUT)
+		} else {
+			s.stop(OUTCOME_CANCELLED)
+		}
+		return StepResult{Done: true}
+	}
+
+	if s.opts.MaxIterations > 0 && s.Grid.Iterations >= s.opts.MaxIterations {
+		s.stop(OUTCOME_ITERATION_LIMIT)
+		return StepResult{Done: true}
+	}
+
+	s.Grid.Iterations++
+	res := s.Solver.Step()
+
+	if res.Done {
+		s.Grid.EndTime = time.Now()
+		s.Grid.Status = s.Solver.Result()
+		s.outcome = OUTCOME_SUCCESS
+		if s.Grid.Status == STATUS_END_NOPATH {
+			s.outcome = OUTCOME_NOPATH
+		}
+	}
+
+	return res
+}
+
+// Replan tells an incremental solver that cells changed and resumes the search so the path gets repaired.
+// The marks left by the previous run are cleared, so the grid only shows what the repair expands.
+// Only walls and weights can be repaired: if Start, End or the search settings changed, the solver starts over.
+// It reports false if the solver is not incremental, and the search has to be started over instead.
+func (s *Search) Replan(changed ...*Node) bool {
+	incremental, ok := s.Solver.(IncrementalSolver)
+	if !ok {
+		return false
+	}
+
+	for i := range s.Grid.Cells {
+		for j := range s.Grid.Cells[i] {
+			node := &s.Grid.Cells[i][j]
+			node.Visited, node.Added, node.IsPath = false, false, false
+		}
+	}
+
+	if s.settingsChanged() {
+		s.Solver.Init(s.Grid)
+		s.keepSettings()
+	} else {
+		for _, n := range changed {
+			incremental.CellChanged(n)
+		}
+	}
+
+	s.Grid.PathLength = 0
+	s.Grid.PathCost = 0
+	s.Grid.Status = STATUS_PATHING
+	s.outcome = ""
+
+	return true
+}
+
+// stop ends the search without a path, leaving the grid idle.
+func (s *Search) stop(outcome Outcome) {
+	s.Grid.EndTime = time.Now()
+	s.Grid.Status = STATUS_IDLE
+	s.outcome = outcome
+}
+
+// Done reports whether the search has finished, for whatever reason.
+func (s *Search) Done() bool {
+	return s.outcome != ""
+}
+
+// Result returns the summary of the search. It is only meaningful once Done reports true.
+func (s *Search) Result() Result {
+	return Result{
+		Outcome:    s.outcome,
+		PathLength: s.Grid.PathLength,
+		PathCost:   s.Grid.PathCost,
+		Iterations: s.Grid.Iterations,
+		Elapsed:    s.Grid.EndTime.Sub(s.Grid.StartTime),
+	}
+}
+
+// Solve searches grid with s until it finishes, without any throttling, and returns its Result.
+func Solve(ctx context.Context, grid *Grid, s Solver, opts Options) Result {
+	search := NewSearch(ctx, grid, s, opts)
+	for !search.Step().Done {
+	}
+
+	return search.Result()
+}
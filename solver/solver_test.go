@@ -0,0 +1,246 @@
+package solver
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"pathfinding/pair"
+	"testing"
+	"time"
+)
+
+var testNeighborhoods = []Neighborhood{NEIGHBORHOOD_4, NEIGHBORHOOD_8, NEIGHBORHOOD_8_NO_CORNERS, NEIGHBORHOOD_HEX}
+
+// randomGrid returns a rows*cols grid with flags in opposite corners, about walls percent of walls, and random
+// terrain weights if weighted is set.
+func randomGrid(rng *rand.Rand, rows, cols int, neighborhood Neighborhood, walls int, weighted bool) Grid {
+	grid := NewGrid(rows, cols, pair.New(rows-1, 0), pair.New(0, cols-1))
+	grid.Neighborhood = neighborhood
+	if neighborhood == NEIGHBORHOOD_8 || neighborhood == NEIGHBORHOOD_8_NO_CORNERS {
+		grid.Heuristic = HEURISTIC_OCTILE
+	}
+
+	weights := []int{WEIGHT_ROAD, WEIGHT_GRASS, WEIGHT_MUD, WEIGHT_WATER}
+	for i := range grid.Cells {
+		for j := range grid.Cells[i] {
+			if grid.IsFlag(pair.New(i, j)) {
+				continue
+			}
+			grid.Cells[i][j].IsWall = rng.Intn(100) < walls
+			if weighted {
+				grid.Cells[i][j].Weight = weights[rng.Intn(len(weights))]
+			}
+		}
+	}
+	return grid
+}
+
+// solve runs the solver registered as name on a clone of grid.
+func solve(t *testing.T, grid *Grid, name string) (Grid, Result) {
+	t.Helper()
+
+	s := New(name)
+	if s == nil {
+		t.Fatalf("no solver registered as %q", name)
+	}
+	clone := grid.Clone()
+	return clone, Solve(context.Background(), &clone, s, Options{})
+}
+
+// checkPath fails t unless the path found on grid links Start to End with moves its Neighborhood allows.
+func checkPath(t *testing.T, grid *Grid, name string) {
+	t.Helper()
+
+	steps := 0
+	for node := grid.End; node.Prev != nil; node = node.Prev {
+		if node.IsWall {
+			t.Fatalf("%s: path crosses the wall at %v", name, node.Coord)
+		}
+		if !neighbors(grid, node.Prev, node) {
+			t.Fatalf("%s: path jumps from %v to %v", name, node.Prev.Coord, node.Coord)
+		}
+		if steps++; steps > len(grid.Cells)*len(grid.Cells[0]) {
+			t.Fatalf("%s: path loops", name)
+		}
+	}
+	if steps > 0 && grid.Start.Prev != nil {
+		t.Fatalf("%s: Start has a previous cell", name)
+	}
+}
+
+// neighbors reports whether the grid Neighborhood allows moving from a to b.
+func neighbors(grid *Grid, a, b *Node) bool {
+	for _, n := range grid.Neighbors(a) {
+		if n == b {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSolversMatchDijkstra(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for _, neighborhood := range testNeighborhoods {
+		for k := 0; k < 150; k++ {
+			grid := randomGrid(rng, 5+rng.Intn(20), 5+rng.Intn(20), neighborhood, 10+rng.Intn(30), true)
+			optimal, _ := solve(t, &grid, "Dijkstra")
+
+			for _, name := range []string{"A*", "Bidirectional A*", "LPA*", "D* Lite", "Flow field"} {
+				solved, result := solve(t, &grid, name)
+				if want := optimal.Status; solved.Status != want {
+					t.Fatalf("%s on %s grid %d: status %s, Dijkstra got %s", name, neighborhood, k, solved.Status, want)
+				}
+				if solved.Status != STATUS_END_SUCCESS {
+					continue
+				}
+				checkPath(t, &solved, name)
+				if math.Abs(result.PathCost-optimal.PathCost) > 1e-6 {
+					t.Fatalf("%s on %s grid %d: path cost %f, Dijkstra got %f", name, neighborhood, k, result.PathCost, optimal.PathCost)
+				}
+			}
+		}
+	}
+}
+
+// JPS and the breadth-first searches ignore terrain weights, so they are compared on grids without any.
+func TestUnweightedSolversMatchDijkstra(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+
+	for _, neighborhood := range testNeighborhoods {
+		for k := 0; k < 300; k++ {
+			grid := randomGrid(rng, 3+rng.Intn(25), 3+rng.Intn(25), neighborhood, rng.Intn(45), false)
+			optimal, _ := solve(t, &grid, "Dijkstra")
+
+			for _, name := range []string{"Jump Point Search", "Breadth-first search", "Bidirectional BFS"} {
+				solved, result := solve(t, &grid, name)
+				if want := optimal.Status; solved.Status != want {
+					t.Fatalf("%s on %s grid %d: status %s, Dijkstra got %s", name, neighborhood, k, solved.Status, want)
+				}
+				if solved.Status != STATUS_END_SUCCESS {
+					continue
+				}
+				checkPath(t, &solved, name)
+
+				// Breadth-first searches find the fewest moves, which only costs the least on 4-way and hex grids
+				if name == "Jump Point Search" || neighborhood == NEIGHBORHOOD_4 || neighborhood == NEIGHBORHOOD_HEX {
+					if math.Abs(result.PathCost-optimal.PathCost) > 1e-6 {
+						t.Fatalf("%s on %s grid %d: path cost %f, Dijkstra got %f", name, neighborhood, k, result.PathCost, optimal.PathCost)
+					}
+				}
+			}
+
+			bfs, _ := solve(t, &grid, "Breadth-first search")
+			if _, result := solve(t, &grid, "Bidirectional BFS"); result.PathLength != bfs.PathLength {
+				t.Fatalf("Bidirectional BFS on %s grid %d: %d cells, Breadth-first search got %d", neighborhood, k, result.PathLength, bfs.PathLength)
+			}
+		}
+	}
+}
+
+// HPA* refines an abstract path, which is not always the shortest, but it finds one whenever there is one.
+func TestHPAStarFindsPaths(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+
+	for _, neighborhood := range testNeighborhoods {
+		for k := 0; k < 200; k++ {
+			grid := randomGrid(rng, 10+rng.Intn(30), 10+rng.Intn(30), neighborhood, 10+rng.Intn(30), rng.Intn(2) == 0)
+			optimal, _ := solve(t, &grid, "Dijkstra")
+
+			solved, result := solve(t, &grid, "HPA*")
+			if want := optimal.Status; solved.Status != want {
+				t.Fatalf("HPA* on %s grid %d: status %s, Dijkstra got %s", neighborhood, k, solved.Status, want)
+			}
+			if solved.Status != STATUS_END_SUCCESS {
+				continue
+			}
+			checkPath(t, &solved, "HPA*")
+			if result.PathCost < optimal.PathCost-1e-6 {
+				t.Fatalf("HPA* on %s grid %d: path cost %f, below the optimal %f", neighborhood, k, result.PathCost, optimal.PathCost)
+			}
+		}
+	}
+}
+
+func TestSearchOutcomes(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		opts Options
+		want Outcome
+	}{
+		{"cancelled", cancelled, Options{}, OUTCOME_CANCELLED},
+		{"expired", expired, Options{}, OUTCOME_TIMEOUT},
+		{"iteration limit", context.Background(), Options{MaxIterations: 1}, OUTCOME_ITERATION_LIMIT},
+		{"success", context.Background(), Options{}, OUTCOME_SUCCESS},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			grid := NewGrid(10, 10, pair.New(9, 0), pair.New(0, 9))
+			result := Solve(test.ctx, &grid, New("Dijkstra"), test.opts)
+
+			if result.Outcome != test.want {
+				t.Fatalf("outcome %s, want %s", result.Outcome, test.want)
+			}
+			if success := grid.Status == STATUS_END_SUCCESS; success != (test.want == OUTCOME_SUCCESS) {
+				t.Fatalf("grid status %s with outcome %s", grid.Status, result.Outcome)
+			}
+		})
+	}
+}
+
+// Replans after editing walls and weights, and after moving the flags, must find what a new search would.
+func TestReplanMatchesDijkstra(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	weights := []int{WEIGHT_ROAD, WEIGHT_GRASS, WEIGHT_MUD, WEIGHT_WATER}
+
+	for _, neighborhood := range testNeighborhoods {
+		for _, name := range []string{"LPA*", "D* Lite"} {
+			for k := 0; k < 30; k++ {
+				grid := randomGrid(rng, 5+rng.Intn(15), 5+rng.Intn(15), neighborhood, 10+rng.Intn(30), true)
+				search := NewSearch(context.Background(), &grid, New(name), Options{})
+				for !search.Step().Done {
+				}
+
+				for edit := 0; edit < 20; edit++ {
+					p := pair.New(rng.Intn(len(grid.Cells)), rng.Intn(len(grid.Cells[0])))
+					node := &grid.Cells[p.I][p.J]
+					switch {
+					case grid.IsFlag(p):
+						continue
+					case edit%7 == 6 && !node.IsWall:
+						grid.Start = node
+					case edit%7 == 3:
+						node.Weight = weights[rng.Intn(len(weights))]
+					default:
+						node.IsWall = !node.IsWall
+					}
+
+					if !search.Replan(node) {
+						t.Fatalf("%s can not replan", name)
+					}
+					for !search.Step().Done {
+					}
+
+					optimal, _ := solve(t, &grid, "Dijkstra")
+					if grid.Status != optimal.Status {
+						t.Fatalf("%s on %s grid %d, edit %d: status %s, Dijkstra got %s", name, neighborhood, k, edit, grid.Status, optimal.Status)
+					}
+					if grid.Status != STATUS_END_SUCCESS {
+						continue
+					}
+					checkPath(t, &grid, name)
+					if math.Abs(grid.PathCost-optimal.PathCost) > 1e-6 {
+						t.Fatalf("%s on %s grid %d, edit %d: path cost %f, Dijkstra got %f", name, neighborhood, k, edit, grid.PathCost, optimal.PathCost)
+					}
+				}
+			}
+		}
+	}
+}
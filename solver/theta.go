@@ -0,0 +1,103 @@
+package solver
+
+import (
+	"container/heap"
+	"math"
+)
+
+// theta is Theta*, an A* whose nodes may take the parent of the node expanding them as their own when
+// there is line of sight between them, so paths run at any angle instead of along cell edges.
+// Links between cells cost their straight line distance, so cell weights are not taken into account.
+type theta struct {
+	name string
+	lazy bool // Lazy Theta* assumes line of sight when relaxing, and only checks it once the node is expanded
+
+	grid *Grid
+	pq   PriorityQueue
+}
+
+func init() {
+	Register("Theta*", func() Solver { return &theta{name: "Theta*"} })
+	Register("Lazy Theta*", func() Solver { return &theta{name: "Lazy Theta*", lazy: true} })
+}
+
+func (t *theta) Name() string {
+	return t.name
+}
+
+func (t *theta) Init(grid *Grid) {
+	t.grid = grid
+
+	for i := range grid.Cells {
+		for j := range grid.Cells[i] {
+			grid.Cells[i][j].Gcost = math.MaxFloat64
+			grid.Cells[i][j].Cost = math.MaxFloat64
+		}
+	}
+
+	grid.Start.Gcost = 0
+	grid.Start.Cost = grid.h(*grid.Start)
+
+	t.pq = PriorityQueue{grid.Start}
+	heap.Init(&t.pq)
+}
+
+func (t *theta) Step() StepResult {
+	if t.pq.Len() == 0 {
+		return StepResult{Done: true}
+	}
+
+	current := heap.Pop(&t.pq).(*Node)
+	current.Visited = true
+
+	if t.lazy && current.Prev != nil && !t.grid.LineOfSight(current.Prev.Coord, current.Coord) {
+		// The assumed line of sight does not exist, fall back to the best expanded neighbour
+		current.Gcost = math.MaxFloat64
+		for _, neighbor := range t.grid.Neighbors(current) {
+			if neighbor.Visited && neighbor.Gcost+t.grid.euclidean(neighbor.Coord, current.Coord) < current.Gcost {
+				current.Prev = neighbor
+				current.Gcost = neighbor.Gcost + t.grid.euclidean(neighbor.Coord, current.Coord)
+			}
+		}
+	}
+
+	if current == t.grid.End {
+		return StepResult{Popped: current, Done: true}
+	}
+
+	var pushed []*Node
+	for _, neighbor := range t.grid.Neighbors(current) {
+		if neighbor.Visited {
+			continue
+		}
+
+		parent := current
+		if current.Prev != nil && (t.lazy || t.grid.LineOfSight(current.Prev.Coord, neighbor.Coord)) {
+			parent = current.Prev
+		}
+
+		gcost := parent.Gcost + t.grid.euclidean(parent.Coord, neighbor.Coord)
+		if gcost < neighbor.Gcost {
+			neighbor.Prev = parent
+			neighbor.Gcost = gcost
+			neighbor.Cost = gcost + t.grid.h(*neighbor)
+
+			if neighbor.Added {
+				heap.Fix(&t.pq, neighbor.index)
+			} else {
+				neighbor.Added = true
+				heap.Push(&t.pq, neighbor)
+			}
+			pushed = append(pushed, neighbor)
+		}
+	}
+
+	return StepResult{Popped: current, Pushed: pushed, Done: t.pq.Len() == 0}
+}
+
+func (t *theta) Result() Status {
+	if !t.grid.constructPath() {
+		return STATUS_END_NOPATH
+	}
+	return STATUS_END_SUCCESS
+}